@@ -81,6 +81,7 @@ var (
 
 		// file open
 		"openat",
+		"openat2",
 
 		// file delete
 		"unlinkat",
@@ -90,6 +91,35 @@ var (
 
 		// permission check
 		"faccessat",
+		"faccessat2",
+		"statx",
+
+		// file create / link / rename
+		"mkdirat",
+		"mknodat",
+		"linkat",
+		"symlinkat",
+		"renameat",
+		"renameat2",
+
+		// file attributes
+		"fchmodat",
+		"fchmodat2",
+		"fchownat",
+		"truncate",
+		"utimensat",
+		"getxattr",
+		"lgetxattr",
+		"listxattr",
+		"llistxattr",
+		"setxattr",
+		"lsetxattr",
+		"removexattr",
+		"lremovexattr",
+
+		// others
+		"chdir",
+		"inotify_add_watch",
 	}
 
 	// process related syscall if allowProc enabled
@@ -138,12 +168,10 @@ var (
 			},
 			FileAccess: FileAccessConfig{
 				ExtraRead: []string{
-					"/usr/bin/python3",
-					"/usr/lib/python3/",
-					"/usr/bin/python3.6",
-					"/usr/lib/python3.6/",
-					"/usr/bin/lib/python3.6/",
-					"/usr/local/lib/python3.6/",
+					"/usr/bin/python3*",
+					"/usr/lib/python3*/",
+					"/usr/bin/lib/python3*/",
+					"/usr/local/lib/python3*/",
 					"/usr/bin/pyvenv.cfg",
 					"/usr/pyvenv.cfg",
 					"/usr/bin/Modules",
@@ -153,7 +181,7 @@ var (
 					"./answer.code",
 				},
 				ExtraStat: []string{
-					"/usr", "/usr/bin", "/usr/lib", "/usr/lib/python36.zip",
+					"/usr", "/usr/bin", "/usr/lib", "/usr/lib/python3*.zip",
 				},
 			},
 			RunCommand: []string{"/usr/bin/python3", "-I", "-B"},
@@ -166,13 +194,12 @@ var (
 					"clock_gettime", "clock_getres",
 					"setrlimit", "pipe",
 					"getdents64", "getdents",
-					"umask", "rename", "chmod", "mkdir",
-					"chdir", "fchdir",
+					"umask", "fchdir",
 					"ftruncate",
 					"sched_getaffinity", "sched_yield",
 					"uname", "sysinfo",
 					"prlimit64", "getrandom",
-					"fchmodat", "rseq",
+					"rseq",
 				},
 				ExtraBan: []string{"socket", "connect", "geteuid", "getuid"},
 			},
@@ -22,5 +22,17 @@ var (
 		"stat",
 		"access",
 		"newfstatat",
+		"mkdir",
+		"rmdir",
+		"mknod",
+		"link",
+		"symlink",
+		"rename",
+		"chmod",
+		"chown",
+		"lchown",
+		"utime",
+		"utimes",
+		"futimesat",
 	}
 )
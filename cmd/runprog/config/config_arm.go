@@ -31,5 +31,16 @@ var (
 		"access",
 		"fstatat",
 		"fstatat64",
+		"mkdir",
+		"rmdir",
+		"mknod",
+		"link",
+		"symlink",
+		"rename",
+		"chmod",
+		"chown",
+		"lchown",
+		"utimes",
+		"futimesat",
 	}
 )
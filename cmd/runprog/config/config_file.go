@@ -0,0 +1,183 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/tobiichi3227/go-sandbox/runner/ptrace/filehandler"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFile reads program configs from a TOML / JSON / YAML file, or from all
+// such files inside a directory (in lexical order), and merges them over the
+// built-in configs. A program type defined in the file replaces the built-in
+// one as a whole and later files override earlier ones.
+func LoadFile(path string) error {
+	c, err := readConfigs(path)
+	if err != nil {
+		return err
+	}
+	maps.Copy(runptraceConfig, c)
+	return nil
+}
+
+// readConfigs reads and validates configs from file or directory
+func readConfigs(path string) (map[string]ProgramConfig, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if !fi.IsDir() {
+		return readConfigFile(path)
+	}
+
+	// os.ReadDir returns entries sorted by file name
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	ret := make(map[string]ProgramConfig)
+	for _, e := range entries {
+		if e.IsDir() || !isConfigFile(e.Name()) {
+			continue
+		}
+		c, err := readConfigFile(filepath.Join(path, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(ret, c)
+	}
+	return ret, nil
+}
+
+func isConfigFile(name string) bool {
+	switch filepath.Ext(name) {
+	case ".json", ".yaml", ".yml", ".toml":
+		return true
+	}
+	return false
+}
+
+// readConfigFile decodes a single file according to its extension, unknown
+// fields are treated as error to catch typos in the config
+func readConfigFile(path string) (map[string]ProgramConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+
+	var c map[string]ProgramConfig
+	switch filepath.Ext(path) {
+	case ".json":
+		d := json.NewDecoder(bytes.NewReader(content))
+		d.DisallowUnknownFields()
+		err = d.Decode(&c)
+
+	case ".yaml", ".yml":
+		d := yaml.NewDecoder(bytes.NewReader(content))
+		d.KnownFields(true)
+		err = d.Decode(&c)
+
+	case ".toml":
+		var md toml.MetaData
+		md, err = toml.Decode(string(content), &c)
+		if err == nil {
+			if u := md.Undecoded(); len(u) > 0 {
+				err = fmt.Errorf("unknown field %q", u[0].String())
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("config: %s: unsupported file type", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+
+	for name, pc := range c {
+		if err := pc.validate(name); err != nil {
+			return nil, fmt.Errorf("config: %s: %w", path, err)
+		}
+	}
+	return c, nil
+}
+
+// validate checks the syscall names, counts and paths in the program config
+func (c *ProgramConfig) validate(name string) error {
+	if name == "" {
+		return fmt.Errorf("empty program type")
+	}
+	for _, s := range []struct {
+		field string
+		names []string
+	}{
+		{"syscall.extraAllow", c.Syscall.ExtraAllow},
+		{"syscall.extraBan", c.Syscall.ExtraBan},
+	} {
+		for _, n := range s.names {
+			if err := checkSyscall(n); err != nil {
+				return fmt.Errorf("%s: %s: %q: %w", name, s.field, n, err)
+			}
+		}
+	}
+	for n, cnt := range c.Syscall.ExtraCount {
+		if err := checkSyscall(n); err != nil {
+			return fmt.Errorf("%s: syscall.extraCount: %q: %w", name, n, err)
+		}
+		if cnt <= 0 {
+			return fmt.Errorf("%s: syscall.extraCount: %q: count should be positive, got %d", name, n, cnt)
+		}
+	}
+
+	for _, s := range []struct {
+		field string
+		paths []string
+	}{
+		{"fileAccess.extraRead", c.FileAccess.ExtraRead},
+		{"fileAccess.extraWrite", c.FileAccess.ExtraWrite},
+		{"fileAccess.extraStat", c.FileAccess.ExtraStat},
+		{"fileAccess.extraBan", c.FileAccess.ExtraBan},
+	} {
+		for _, p := range s.paths {
+			if err := checkPath(p); err != nil {
+				return fmt.Errorf("%s: %s: %q: %w", name, s.field, p, err)
+			}
+		}
+	}
+
+	if len(c.RunCommand) > 0 && !filepath.IsAbs(c.RunCommand[0]) {
+		return fmt.Errorf("%s: runCommand: %q: executable should be an absolute path", name, c.RunCommand[0])
+	}
+	return nil
+}
+
+// checkPath ensures the path could be matched by the FileSet. Directory
+// entries end with "/" and "/*" matches the first level only. Entries could be
+// glob / regex patterns and prefixed by "!" to deny
+func checkPath(p string) error {
+	p = strings.TrimPrefix(p, filehandler.DenyPrefix)
+	if p == "" {
+		return fmt.Errorf("empty path")
+	}
+	if strings.IndexByte(p, 0) >= 0 {
+		return fmt.Errorf("path contains NUL byte")
+	}
+	if err := filehandler.ValidatePattern(p); err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+	if !filepath.IsAbs(p) {
+		// relative path is joined with the work path
+		return nil
+	}
+	base := strings.TrimSuffix(strings.TrimSuffix(p, "*"), "/")
+	if base != "" && filepath.Clean(base) != base {
+		return fmt.Errorf("path is not clean (expect %q)", filepath.Clean(base))
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+//go:build linux
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testJSONConfig = `{
+	"java": {
+		"syscall": {
+			"extraAllow": ["futex", "clone"],
+			"extraCount": {"set_tid_address": 1}
+		},
+		"fileAccess": {
+			"extraRead": ["/usr/lib/jvm/", "./Main.class"]
+		},
+		"runCommand": ["/usr/bin/java", "-Xss64m"]
+	}
+}`
+
+	testYAMLConfig = `
+python3:
+  syscall:
+    extraAllow: [futex, getrandom]
+  fileAccess:
+    extraRead: [/usr/lib/python3/]
+    extraStat: [/usr]
+  runCommand: [/usr/bin/python3, -I]
+`
+
+	testTOMLConfig = `
+[ruby]
+runCommand = ["/usr/bin/ruby"]
+
+[ruby.syscall]
+extraAllow = ["futex"]
+
+[ruby.fileAccess]
+extraRead = ["/usr/lib/ruby/"]
+`
+)
+
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestReadConfigs_File(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, file, content, pType, exe string
+	}{
+		{"JSON", "java.json", testJSONConfig, "java", "/usr/bin/java"},
+		{"YAML", "python.yaml", testYAMLConfig, "python3", "/usr/bin/python3"},
+		{"TOML", "ruby.toml", testTOMLConfig, "ruby", "/usr/bin/ruby"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := readConfigs(writeTestFile(t, dir, tt.file, tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			pc, ok := c[tt.pType]
+			if !ok {
+				t.Fatalf("program type %q not found in %v", tt.pType, c)
+			}
+			if len(pc.RunCommand) == 0 || pc.RunCommand[0] != tt.exe {
+				t.Errorf("RunCommand = %v, expected %q", pc.RunCommand, tt.exe)
+			}
+			if len(pc.Syscall.ExtraAllow) == 0 || len(pc.FileAccess.ExtraRead) == 0 {
+				t.Errorf("config not decoded: %+v", pc)
+			}
+		})
+	}
+}
+
+func TestReadConfigs_Dir(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "a.json", testJSONConfig)
+	writeTestFile(t, dir, "b.yaml", testYAMLConfig)
+	// later file overrides earlier ones
+	writeTestFile(t, dir, "c.toml", `[java]
+runCommand = ["/opt/java/bin/java"]
+`)
+	writeTestFile(t, dir, "README.md", "not a config")
+
+	c, err := readConfigs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c) != 2 {
+		t.Fatalf("expected 2 program types, got %v", c)
+	}
+	if got := c["java"].RunCommand; len(got) != 1 || got[0] != "/opt/java/bin/java" {
+		t.Errorf("java RunCommand = %v, expected override from c.toml", got)
+	}
+}
+
+func TestReadConfigs_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, file, content, errContains string
+	}{
+		{
+			name:        "Unknown syscall",
+			file:        "s.json",
+			content:     `{"c": {"syscall": {"extraAllow": ["read", "not_a_syscall"]}}}`,
+			errContains: `"not_a_syscall"`,
+		},
+		{
+			name:        "Unknown counted syscall",
+			file:        "sc.yaml",
+			content:     "c:\n  syscall:\n    extraCount:\n      not_a_syscall: 1\n",
+			errContains: `syscall.extraCount: "not_a_syscall"`,
+		},
+		{
+			name:        "Non positive count",
+			file:        "cnt.json",
+			content:     `{"c": {"syscall": {"extraCount": {"futex": 0}}}}`,
+			errContains: `"futex": count should be positive`,
+		},
+		{
+			name:        "Unclean path",
+			file:        "p.toml",
+			content:     "[c.fileAccess]\nextraRead = [\"/usr/lib/../etc/\"]\n",
+			errContains: `fileAccess.extraRead: "/usr/lib/../etc/"`,
+		},
+		{
+			name:        "Empty path",
+			file:        "e.json",
+			content:     `{"c": {"fileAccess": {"extraWrite": [""]}}}`,
+			errContains: "fileAccess.extraWrite",
+		},
+		{
+			name:        "Invalid glob",
+			file:        "g.json",
+			content:     `{"c": {"fileAccess": {"extraRead": ["/usr/lib/[a-"]}}}`,
+			errContains: "invalid pattern",
+		},
+		{
+			name:        "Invalid regex",
+			file:        "re.yaml",
+			content:     "c:\n  fileAccess:\n    extraRead: [\"!re:(\"]\n",
+			errContains: "invalid pattern",
+		},
+		{
+			name:        "Relative run command",
+			file:        "r.json",
+			content:     `{"c": {"runCommand": ["python3"]}}`,
+			errContains: `runCommand: "python3"`,
+		},
+		{
+			name:        "Unknown field",
+			file:        "u.yaml",
+			content:     "c:\n  fileAcess:\n    extraRead: [/usr/]\n",
+			errContains: "fileAcess",
+		},
+		{
+			name:        "Unsupported file type",
+			file:        "c.ini",
+			content:     "",
+			errContains: "unsupported file type",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readConfigs(writeTestFile(t, dir, tt.file, tt.content))
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("error %q does not contain %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	orig := runptraceConfig["python3"]
+	t.Cleanup(func() {
+		runptraceConfig["python3"] = orig
+	})
+
+	p := writeTestFile(t, t.TempDir(), "python.yml", testYAMLConfig)
+	if err := LoadFile(p); err != nil {
+		t.Fatal(err)
+	}
+	args, _, _, _ := GetConf("python3", "/w", []string{"a.py"}, nil, nil, false)
+	if len(args) != 3 || args[0] != "/usr/bin/python3" || args[1] != "-I" || args[2] != "a.py" {
+		t.Errorf("args = %v, expected loaded python3 run command", args)
+	}
+	if _, ok := runptraceConfig["compiler"]; !ok {
+		t.Errorf("built-in config should be kept")
+	}
+}
@@ -0,0 +1,34 @@
+//go:build linux
+
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGetConf_Syscalls(t *testing.T) {
+	// path taking syscalls checked by the ptrace handler
+	traced := []string{
+		"openat", "openat2", "faccessat2", "statx", "mkdirat", "renameat2",
+		"linkat", "symlinkat", "fchmodat", "fchownat", "truncate", "chdir",
+	}
+	for _, pType := range []string{"default", "compiler", "python3"} {
+		t.Run(pType, func(t *testing.T) {
+			_, allow, trace, _ := GetConf(pType, "/w", []string{"/w/a"}, nil, nil, true)
+			for _, n := range append(allow, trace...) {
+				if err := checkSyscall(n); err != nil {
+					t.Errorf("checkSyscall(%q): %v", n, err)
+				}
+			}
+			for _, n := range traced {
+				if !slices.Contains(trace, n) {
+					t.Errorf("%s is not traced", n)
+				}
+				if slices.Contains(allow, n) {
+					t.Errorf("%s is allowed without check", n)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package config
+
+import "github.com/tobiichi3227/go-sandbox/pkg/seccomp/libseccomp"
+
+// checkSyscall ensures the syscall name exists on the current architecture
+func checkSyscall(name string) error {
+	_, err := libseccomp.ToSyscallNo(name)
+	return err
+}
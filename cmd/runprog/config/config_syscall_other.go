@@ -0,0 +1,7 @@
+//go:build !linux
+
+package config
+
+func checkSyscall(name string) error {
+	return nil
+}
@@ -2,18 +2,22 @@ package config
 
 // ProgramConfig defines the extra config apply to program type
 type ProgramConfig struct {
-	Syscall    SyscallConfig
-	FileAccess FileAccessConfig
-	RunCommand []string
+	Syscall    SyscallConfig    `json:"syscall" yaml:"syscall" toml:"syscall"`
+	FileAccess FileAccessConfig `json:"fileAccess" yaml:"fileAccess" toml:"fileAccess"`
+	RunCommand []string         `json:"runCommand" yaml:"runCommand" toml:"runCommand"`
 }
 
 // SyscallConfig defines extra syscallConfig apply to program type
 type SyscallConfig struct {
-	ExtraAllow, ExtraBan []string
-	ExtraCount           map[string]int
+	ExtraAllow []string       `json:"extraAllow" yaml:"extraAllow" toml:"extraAllow"`
+	ExtraBan   []string       `json:"extraBan" yaml:"extraBan" toml:"extraBan"`
+	ExtraCount map[string]int `json:"extraCount" yaml:"extraCount" toml:"extraCount"`
 }
 
 // FileAccessConfig defines extra file access permission for the program type
 type FileAccessConfig struct {
-	ExtraRead, ExtraWrite, ExtraStat, ExtraBan []string
+	ExtraRead  []string `json:"extraRead" yaml:"extraRead" toml:"extraRead"`
+	ExtraWrite []string `json:"extraWrite" yaml:"extraWrite" toml:"extraWrite"`
+	ExtraStat  []string `json:"extraStat" yaml:"extraStat" toml:"extraStat"`
+	ExtraBan   []string `json:"extraBan" yaml:"extraBan" toml:"extraBan"`
 }
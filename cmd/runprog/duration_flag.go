@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// durationFlag is a time.Duration flag that also accepts number in seconds
+// (e.g. "1", "1.5" or "1500ms")
+type durationFlag time.Duration
+
+func (d *durationFlag) String() string {
+	return time.Duration(*d).String()
+}
+
+func (d *durationFlag) Set(value string) error {
+	var v time.Duration
+	if s, err := strconv.ParseFloat(value, 64); err == nil {
+		v = time.Duration(s * float64(time.Second))
+	} else if v, err = time.ParseDuration(value); err != nil {
+		return err
+	}
+	if v < 0 {
+		return fmt.Errorf("negative duration: %s", value)
+	}
+	*d = durationFlag(v)
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDurationFlag(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		err      bool
+	}{
+		{"1", time.Second, false},
+		{"1.5", 1500 * time.Millisecond, false},
+		{"1500ms", 1500 * time.Millisecond, false},
+		{"2s", 2 * time.Second, false},
+		{"0", 0, false},
+		{"-1", 0, true},
+		{"-1s", 0, true},
+		{"abc", 0, true},
+	}
+	for _, tt := range tests {
+		var d durationFlag
+		err := d.Set(tt.value)
+		if tt.err {
+			if err == nil {
+				t.Errorf("Set(%q) = %v; expected error", tt.value, time.Duration(d))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Set(%q) failed: %v", tt.value, err)
+			continue
+		}
+		if time.Duration(d) != tt.expected {
+			t.Errorf("Set(%q) = %v; expected %v", tt.value, time.Duration(d), tt.expected)
+		}
+	}
+}
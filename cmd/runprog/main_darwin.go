@@ -15,10 +15,13 @@ import (
 )
 
 var (
-	timeLimit, realTimeLimit, memoryLimit, outputLimit, stackLimit uint64
-	inputFileName, outputFileName, errorFileName, workPath         string
+	memoryLimit, outputLimit, stackLimit                   uint64
+	timeLimit                                              = durationFlag(time.Second)
+	realTimeLimit                                          durationFlag
+	inputFileName, outputFileName, errorFileName, workPath string
 
 	profilePath, result string
+	rlimitSpec          string
 	showDetails         bool
 
 	args []string
@@ -26,8 +29,8 @@ var (
 
 func main() {
 	flag.Usage = printUsage
-	flag.Uint64Var(&timeLimit, "tl", 1, "Set time limit (in second)")
-	flag.Uint64Var(&realTimeLimit, "rtl", 0, "Set real time limit (in second)")
+	flag.Var(&timeLimit, "tl", "Set time limit (in second, or duration like 1500ms)")
+	flag.Var(&realTimeLimit, "rtl", "Set real time limit (in second, or duration like 1500ms)")
 	flag.Uint64Var(&memoryLimit, "ml", 256, "Set memory limit (in mb)")
 	flag.Uint64Var(&outputLimit, "ol", 64, "Set output limit (in mb)")
 	flag.Uint64Var(&stackLimit, "sl", 32, "Set stack limit (in mb)")
@@ -38,6 +41,7 @@ func main() {
 	flag.StringVar(&profilePath, "p", "", "sandbox profile")
 	flag.BoolVar(&showDetails, "show-trace-details", false, "Show trace details")
 	flag.StringVar(&result, "res", "stdout", "Set the file name for output the result")
+	flag.StringVar(&rlimitSpec, "rlimit", "", "Set additional rlimits (e.g. stack=64m,nofile=256,nproc=1)")
 	flag.Parse()
 
 	args = flag.Args()
@@ -46,7 +50,7 @@ func main() {
 	}
 
 	if realTimeLimit < timeLimit {
-		realTimeLimit = timeLimit + 2
+		realTimeLimit = timeLimit + durationFlag(2*time.Second)
 	}
 	if stackLimit > memoryLimit {
 		stackLimit = memoryLimit
@@ -133,13 +137,16 @@ func start() (*runner.Result, error) {
 	}
 
 	rlims := rlimit.RLimits{
-		CPU:          timeLimit,
-		CPUHard:      realTimeLimit,
+		CPU:          rlimit.CPUSeconds(time.Duration(timeLimit)),
+		CPUHard:      rlimit.CPUSeconds(time.Duration(realTimeLimit)),
 		FileSize:     outputLimit << 20,
 		Data:         memoryLimit << 20,
 		AddressSpace: memoryLimit << 20,
 		Stack:        stackLimit << 20,
 	}
+	if err := rlims.Set(rlimitSpec); err != nil {
+		return nil, err
+	}
 
 	debug(rlims)
 	debug(args)
@@ -186,7 +193,7 @@ func start() (*runner.Result, error) {
 			SetUpTime:   mTime.Sub(sTime),
 			RunningTime: fTime.Sub(mTime),
 		}
-		if uint64(result.Time) > timeLimit*1e9 {
+		if result.Time > time.Duration(timeLimit) {
 			result.Status = runner.StatusTimeLimitExceeded
 		}
 		if uint64(result.Memory) > memoryLimit<<20 {
@@ -9,6 +9,7 @@ import (
 	"io"
 	"os"
 	"os/signal"
+	"strings"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -32,12 +33,15 @@ import (
 var (
 	addReadable, addWritable, addRawReadable, addRawWritable       arrayFlags
 	allowProc, unsafe, showDetails, useCGroup, memfile, cred, nucg bool
-	timeLimit, realTimeLimit, memoryLimit, outputLimit, stackLimit uint64
+	memoryLimit, outputLimit, stackLimit                           uint64
+	timeLimit                                                      = durationFlag(time.Second)
+	realTimeLimit                                                  durationFlag
 	inputFileName, outputFileName, errorFileName, workPath, runt   string
 
-	useCGroupFd   bool
-	pType, result string
-	args          []string
+	useCGroupFd, freeze       bool
+	pType, result, configPath string
+	rlimitSpec                string
+	args                      []string
 )
 
 // container init
@@ -47,8 +51,8 @@ func init() {
 
 func main() {
 	flag.Usage = printUsage
-	flag.Uint64Var(&timeLimit, "tl", 1, "Set time limit (in second)")
-	flag.Uint64Var(&realTimeLimit, "rtl", 0, "Set real time limit (in second)")
+	flag.Var(&timeLimit, "tl", "Set time limit (in second, or duration like 1500ms)")
+	flag.Var(&realTimeLimit, "rtl", "Set real time limit (in second, or duration like 1500ms)")
 	flag.Uint64Var(&memoryLimit, "ml", 256, "Set memory limit (in mb)")
 	flag.Uint64Var(&outputLimit, "ol", 64, "Set output limit (in mb)")
 	flag.Uint64Var(&stackLimit, "sl", 1024, "Set stack limit (in mb)")
@@ -57,7 +61,9 @@ func main() {
 	flag.StringVar(&errorFileName, "err", "", "Set error file name")
 	flag.StringVar(&workPath, "work-path", "", "Set the work path of the program")
 	flag.StringVar(&pType, "type", "default", "Set the program type (for some program such as python)")
+	flag.StringVar(&configPath, "config", "", "Load program type configs from a TOML / JSON / YAML file or directory")
 	flag.StringVar(&result, "res", "stdout", "Set the file name for output the result")
+	flag.StringVar(&rlimitSpec, "rlimit", "", "Set additional rlimits (e.g. stack=64m,nofile=256,nproc=1)")
 	flag.Var(&addReadable, "add-readable", "Add a readable file")
 	flag.Var(&addWritable, "add-writable", "Add a writable file")
 	flag.BoolVar(&unsafe, "unsafe", false, "Don't check dangerous syscalls")
@@ -67,6 +73,7 @@ func main() {
 	flag.Var(&addRawWritable, "add-writable-raw", "Add a writable file (don't transform to its real path)")
 	flag.BoolVar(&useCGroup, "cgroup", false, "Use cgroup to colloct resource usage")
 	flag.BoolVar(&useCGroupFd, "cgroupfd", false, "Use cgroup FD to clone3 (cgroup v2 & kernel > 5.7)")
+	flag.BoolVar(&freeze, "freeze", false, "Freeze the cgroup before kill when limit exceeded")
 	flag.BoolVar(&memfile, "memfd", false, "Use memfd as exec file")
 	flag.StringVar(&runt, "runner", "ptrace", "Runner for the program (ptrace, ns, container)")
 	flag.BoolVar(&cred, "cred", false, "Generate credential for containers (uid=10000)")
@@ -78,8 +85,15 @@ func main() {
 		printUsage()
 	}
 
+	if configPath != "" {
+		if err := config.LoadFile(configPath); err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+			os.Exit(2)
+		}
+	}
+
 	if realTimeLimit < timeLimit {
-		realTimeLimit = timeLimit + 2
+		realTimeLimit = timeLimit + durationFlag(2*time.Second)
 	}
 	if stackLimit > memoryLimit {
 		stackLimit = memoryLimit
@@ -166,15 +180,15 @@ func start() (*runner.Result, error) {
 		WithBind("/usr", "usr", true).
 		// java wants /proc/self/exe as it need relative path for lib
 		// however, /proc gives interface like /proc/1/fd/3 ..
-		// it is fine since open that file will be a EPERM
-		// changing the fs uid and gid would be a good idea
-		WithProc().
+		// hidepid=2 (invisible) hides processes of other users, the numeric
+		// value is used since the name is not supported before kernel 5.8
+		WithProcOptions(mount.ProcOptions{HidePid: "2"}).
 		// some compiler have multiple version
 		WithBind("/etc/alternatives", "etc/alternatives", true).
 		// fpc wants /etc/fpc.cfg
 		WithBind("/etc/fpc.cfg", "etc/fpc.cfg", true).
-		// go wants /dev/null
-		WithBind("/dev/null", "dev/null", false).
+		// go wants /dev/null, others want /dev/zero, /dev/urandom
+		WithDevices().
 		// ghc wants /var/lib/ghc
 		WithBind("/var/lib/ghc", "var/lib/ghc", true).
 		// work dir
@@ -206,7 +220,13 @@ func start() (*runner.Result, error) {
 		if err != nil {
 			return nil, err
 		}
-		defer cg.Destroy()
+		defer func() {
+			// kill processes escaped from the process group before removal
+			if err := cg.Kill(); err != nil {
+				debug("cgroup kill:", err)
+			}
+			cg.Destroy()
+		}()
 		if err = cg.SetMemoryLimit(memoryLimit << 20); err != nil {
 			return nil, err
 		}
@@ -224,7 +244,32 @@ func start() (*runner.Result, error) {
 		}
 	}
 
-	var syncFunc func(pid int) error
+	// resource usage read with the program frozen before kill
+	var (
+		freezeOnLimit *runner.FreezeOnLimit
+		frozen        *runner.Result
+	)
+	if cg != nil && freeze {
+		freezeOnLimit = &runner.FreezeOnLimit{
+			Freezer: cg,
+			OnFrozen: func() {
+				cpu, _ := cg.CPUUsage()
+				memory, _ := cg.MemoryUsage()
+				procs, _ := cg.Processes()
+				debug("frozen: cpu: ", cpu, " memory: ", memory, " procs: ", procs)
+				frozen = &runner.Result{
+					Time:     time.Duration(cpu),
+					Memory:   runner.Size(memory),
+					ProcPeak: uint64(len(procs)),
+				}
+			},
+		}
+	}
+
+	var (
+		syncFunc func(pid int) error
+		cpuUsage func() (time.Duration, error)
+	)
 	if cg != nil {
 		syncFunc = func(pid int) error {
 			if err := cg.AddProc(pid); err != nil {
@@ -232,6 +277,23 @@ func start() (*runner.Result, error) {
 			}
 			return nil
 		}
+		cpuUsage = func() (time.Duration, error) {
+			cpu, err := cg.CPUUsage()
+			return time.Duration(cpu), err
+		}
+	} else if runt != "container" {
+		// pid inside container is not visible to the host
+		var syncPid atomic.Int64
+		syncFunc = func(pid int) error {
+			syncPid.Store(int64(pid))
+			return nil
+		}
+		cpuUsage = func() (time.Duration, error) {
+			if pid := syncPid.Load(); pid > 0 {
+				return runner.ProcCPUUsage(int(pid))
+			}
+			return 0, nil
+		}
 	}
 
 	if memfile {
@@ -267,14 +329,17 @@ func start() (*runner.Result, error) {
 	}
 
 	rlims := rlimit.RLimits{
-		CPU:         timeLimit,
-		CPUHard:     realTimeLimit,
+		CPU:         rlimit.CPUSeconds(time.Duration(timeLimit)),
+		CPUHard:     rlimit.CPUSeconds(time.Duration(realTimeLimit)),
 		FileSize:    outputLimit << 20,
 		Stack:       stackLimit << 20,
 		Data:        memoryLimit << 20,
 		OpenFile:    256,
 		DisableCore: true,
 	}
+	if err := rlims.Set(rlimitSpec); err != nil {
+		return nil, err
+	}
 	debug("rlimit: ", rlims)
 
 	actionDefault := libseccomp.ActionKill
@@ -300,7 +365,7 @@ func start() (*runner.Result, error) {
 	}
 
 	limit := runner.Limit{
-		TimeLimit:   time.Duration(timeLimit) * time.Second,
+		TimeLimit:   time.Duration(timeLimit),
 		MemoryLimit: runner.Size(memoryLimit << 20),
 	}
 
@@ -351,6 +416,7 @@ func start() (*runner.Result, error) {
 				SyncFunc:      syncFunc,
 				CgroupFD:      cgroupFd,
 				SyncAfterExec: cg == nil || cgDir != nil,
+				FreezeOnLimit: freezeOnLimit,
 			},
 		}
 	} else if runt == "ns" {
@@ -374,6 +440,8 @@ func start() (*runner.Result, error) {
 			SyncFunc:    syncFunc,
 			HostName:    "run_program",
 			DomainName:  "run_program",
+
+			FreezeOnLimit: freezeOnLimit,
 		}
 	} else if runt == "ptrace" {
 		r = &ptrace.Runner{
@@ -389,18 +457,29 @@ func start() (*runner.Result, error) {
 			Unsafe:      unsafe,
 			Handler:     h,
 			SyncFunc:    syncFunc,
+
+			FreezeOnLimit: freezeOnLimit,
 		}
 	} else {
 		return nil, fmt.Errorf("invalid runner type: %s", runt)
 	}
 
+	// rlimit only enforces cpu limit in whole seconds
+	if cpuUsage != nil {
+		r = &runner.CPULimit{
+			Runner:    r,
+			TimeLimit: limit.TimeLimit,
+			Usage:     cpuUsage,
+		}
+	}
+
 	// gracefully shutdown
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt)
 
 	// Run tracer
 	sTime := time.Now()
-	c, cancel := context.WithTimeout(context.Background(), time.Duration(int64(realTimeLimit)*int64(time.Second)))
+	c, cancel := context.WithTimeout(context.Background(), time.Duration(realTimeLimit))
 	defer cancel()
 
 	s := make(chan runner.Result, 1)
@@ -449,6 +528,13 @@ func start() (*runner.Result, error) {
 		debug("cgroup: cpu: ", cpu, " memory: ", memory, " procPeak: ", procPeak)
 		debug("cgroup:", rt)
 	}
+	// usage after kill includes the time to deliver SIGKILL
+	if frozen != nil {
+		rt.Time = frozen.Time
+		rt.Memory = max(rt.Memory, frozen.Memory)
+		rt.ProcPeak = max(rt.ProcPeak, frozen.ProcPeak)
+		debug("frozen:", rt)
+	}
 	if rt.Status == runner.StatusTimeLimitExceeded || rt.Status == runner.StatusNormal {
 		if rt.Time > limit.TimeLimit {
 			rt.Status = runner.StatusTimeLimitExceeded
@@ -463,6 +549,13 @@ func start() (*runner.Result, error) {
 			rt.Status = runner.StatusNormal
 		}
 	}
+	// managed runtimes report uncaught exception by exit code
+	switch {
+	case strings.HasPrefix(pType, "python"):
+		runner.PythonExitCodes.Classify(&rt)
+	case strings.HasPrefix(pType, "java"):
+		runner.JavaExitCodes.Classify(&rt)
+	}
 	return &rt, nil
 }
 
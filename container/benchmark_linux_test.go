@@ -3,18 +3,49 @@ package container
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"runtime"
+	"strconv"
+	"strings"
 	"syscall"
 	"testing"
+	"time"
 
+	"github.com/tobiichi3227/go-sandbox/pkg/forkexec"
+	"github.com/tobiichi3227/go-sandbox/pkg/landlock"
+	"github.com/tobiichi3227/go-sandbox/pkg/mount"
 	"github.com/tobiichi3227/go-sandbox/runner"
+	"golang.org/x/sys/unix"
 )
 
+// deterministicEnv makes the test binary print the personality and the
+// monotonic clock when executed inside the container
+const deterministicEnv = "CONTAINER_TEST_DETERMINISTIC"
+
+// addrNoRandomize is the ADDR_NO_RANDOMIZE personality flag
+const addrNoRandomize = 0x0040000
+
 func init() {
+	if os.Getenv(deterministicEnv) == "1" {
+		printDeterministic()
+	}
 	Init()
 }
 
+func printDeterministic() {
+	p, err := os.ReadFile("/proc/self/personality")
+	if err != nil {
+		os.Exit(2)
+	}
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		os.Exit(2)
+	}
+	fmt.Printf("%s%d\n", p, ts.Sec)
+	os.Exit(0)
+}
+
 func BenchmarkContainer(b *testing.B) {
 	tmpDir, err := os.MkdirTemp("", "")
 	if err != nil {
@@ -138,6 +169,110 @@ func TestContainerSetCred(t *testing.T) {
 	runTest(t, successParam, runner.StatusNormal, credgen{})
 }
 
+// directIDMapper writes the id mappings like newuidmap / newgidmap
+type directIDMapper struct {
+	called bool
+}
+
+func (d *directIDMapper) MapIDs(pid int, uidMappings, gidMappings []syscall.SysProcIDMap) error {
+	d.called = true
+	for n, m := range map[string][]syscall.SysProcIDMap{"uid_map": uidMappings, "gid_map": gidMappings} {
+		var content string
+		for _, im := range m {
+			content += fmt.Sprintf("%d %d %d\n", im.ContainerID, im.HostID, im.Size)
+		}
+		if err := os.WriteFile(fmt.Sprintf("/proc/%d/%s", pid, n), []byte(content), 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestContainerIDMapper(t *testing.T) {
+	t.Parallel()
+	if os.Geteuid() != 0 {
+		t.Skip("root required for this test")
+	}
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Remove(tmpDir)
+	})
+	mapper := &directIDMapper{}
+	builder := &Builder{
+		Root:          tmpDir,
+		Stderr:        os.Stderr,
+		CredGenerator: credgen{},
+		IDMapper:      mapper,
+	}
+	m, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		m.Destroy()
+	})
+	if !mapper.called {
+		t.Error("id mapper not called")
+	}
+	r := m.Execve(context.TODO(), successParam)
+	if r.Status != runner.StatusNormal {
+		t.Fatal(r.Status, r.Error, r)
+	}
+}
+
+// TestContainerIDMapperNoUserNS checks that the mapper is ignored without
+// the user namespace
+func TestContainerIDMapperNoUserNS(t *testing.T) {
+	t.Parallel()
+	if os.Geteuid() != 0 {
+		t.Skip("root required for this test")
+	}
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Remove(tmpDir)
+	})
+	mapper := &directIDMapper{}
+	builder := &Builder{
+		Root:          tmpDir,
+		Stderr:        os.Stderr,
+		CredGenerator: credgen{},
+		IDMapper:      mapper,
+		CloneFlags:    forkexec.UnshareFlags &^ syscall.CLONE_NEWUSER,
+	}
+	m, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		m.Destroy()
+	})
+	if mapper.called {
+		t.Error("id mapper called without user namespace")
+	}
+	r := m.Execve(context.TODO(), successParam)
+	if r.Status != runner.StatusNormal {
+		t.Fatal(r.Status, r.Error, r)
+	}
+}
+
+func TestContainerDevices(t *testing.T) {
+	t.Parallel()
+	m := getEnv(t, nil)
+	r := m.Execve(context.TODO(), ExecveParam{
+		Args: []string{"/bin/sh", "-c", "head -c 8 /dev/zero > /dev/null && head -c 8 /dev/urandom > /dev/null"},
+		Env:  []string{"PATH=/usr/bin:/bin"},
+	})
+	if r.Status != runner.StatusNormal {
+		t.Fatal(r.Status, r.Error, r)
+	}
+}
+
 func runTest(t *testing.T, param ExecveParam, expected runner.Status, credGen CredGenerator) {
 	t.Parallel()
 	m := getEnv(t, credGen)
@@ -185,3 +320,143 @@ func getEnv(t *testing.T, credGen CredGenerator) Environment {
 	})
 	return m
 }
+
+func TestContainerDeterministic(t *testing.T) {
+	t.Parallel()
+	if _, err := os.Stat("/proc/self/timens_offsets"); err != nil {
+		t.Skip("time namespace is not supported")
+	}
+	tmpDir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.Remove(tmpDir)
+	})
+	exe, err := os.Open("/proc/self/exe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exe.Close()
+	out, err := os.CreateTemp(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	const monotonic = 1000000 * time.Second
+	builder := &Builder{
+		Root:        tmpDir,
+		Mounts:      mount.NewDefaultBuilder().WithProc().WithTmpfs("w", "").FilterNotExist().Mounts,
+		Stderr:      os.Stderr,
+		DisableASLR: true,
+		TimeOffsets: &forkexec.TimeOffsets{Monotonic: monotonic, Boottime: time.Hour},
+	}
+	m, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		m.Destroy()
+	})
+	var before, after unix.Timespec
+	unix.ClockGettime(unix.CLOCK_MONOTONIC, &before)
+	r := m.Execve(context.TODO(), ExecveParam{
+		Args:     []string{"./test"},
+		Env:      []string{deterministicEnv + "=1"},
+		Files:    []uintptr{0, out.Fd(), 2},
+		ExecFile: exe.Fd(),
+	})
+	unix.ClockGettime(unix.CLOCK_MONOTONIC, &after)
+	if r.Status != runner.StatusNormal {
+		t.Fatal(r.Status, r.Error, r)
+	}
+
+	b, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	personality, clock, _ := strings.Cut(strings.TrimSpace(string(b)), "\n")
+	if p, err := strconv.ParseUint(personality, 16, 32); err != nil || p&addrNoRandomize == 0 {
+		t.Errorf("personality = %s, expected ADDR_NO_RANDOMIZE", personality)
+	}
+	lo, hi := before.Sec+int64(monotonic.Seconds()), after.Sec+int64(monotonic.Seconds())
+	if c, err := strconv.ParseInt(clock, 10, 64); err != nil || c < lo || c > hi {
+		t.Errorf("monotonic clock = %s, expected in [%d, %d]", clock, lo, hi)
+	}
+}
+
+type recordFreezer struct {
+	calls []string
+}
+
+func (f *recordFreezer) Freeze() error {
+	f.calls = append(f.calls, "freeze")
+	return nil
+}
+
+func (f *recordFreezer) Thaw() error {
+	f.calls = append(f.calls, "thaw")
+	return nil
+}
+
+func TestContainerFreezeOnLimit(t *testing.T) {
+	t.Parallel()
+	m := getEnv(t, nil)
+	f := &recordFreezer{}
+	c, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	r := m.Execve(c, ExecveParam{
+		Args: []string{"/bin/sleep", "10"},
+		Env:  []string{"PATH=/bin"},
+		FreezeOnLimit: &runner.FreezeOnLimit{
+			Freezer:  f,
+			OnFrozen: func() { f.calls = append(f.calls, "frozen") },
+		},
+	})
+	if r.Status == runner.StatusNormal {
+		t.Fatal(r.Status, r.Error, r)
+	}
+	if got := fmt.Sprint(f.calls); got != "[freeze frozen thaw]" {
+		t.Errorf("unexpected freezer calls: %s", got)
+	}
+}
+
+func TestContainerLandlock(t *testing.T) {
+	t.Parallel()
+	if landlock.ABI() == 0 {
+		t.Skip("landlock is not supported")
+	}
+	var sys []string
+	for _, p := range []string{"/bin", "/usr", "/lib", "/lib64", "/etc"} {
+		if _, err := os.Stat(p); err == nil {
+			sys = append(sys, p)
+		}
+	}
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+
+	m := getEnv(t, nil)
+	tests := []struct {
+		name     string
+		dir      string
+		expected runner.Status
+	}{
+		{"allowed", "/bin", runner.StatusNormal},
+		{"denied", "/", runner.StatusNonzeroExitStatus},
+	}
+	for _, tc := range tests {
+		r := m.Execve(context.TODO(), ExecveParam{
+			Args:     []string{"/bin/ls", tc.dir},
+			Env:      []string{"PATH=/bin"},
+			Files:    []uintptr{0, null.Fd(), null.Fd()},
+			Landlock: (&landlock.Ruleset{}).WithPath(landlock.AccessReadExec, sys...),
+		})
+		if r.Status != tc.expected {
+			t.Errorf("%s: %v %v, expected %v", tc.name, r.Status, r.Error, tc.expected)
+		}
+	}
+}
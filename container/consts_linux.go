@@ -1,5 +1,7 @@
 package container
 
+import "golang.org/x/sys/unix"
+
 type cmdType int8
 
 const (
@@ -23,25 +25,26 @@ const (
 	containerMaxProc = 1
 )
 
+// mapperAmbientCaps are capabilities of the container init when the id mappings
+// are written after execve
+var mapperAmbientCaps = []uintptr{
+	unix.CAP_CHOWN,
+	unix.CAP_DAC_OVERRIDE,
+	unix.CAP_FOWNER,
+	unix.CAP_KILL,
+	unix.CAP_SETGID,
+	unix.CAP_SETUID,
+	unix.CAP_SETPCAP,
+	unix.CAP_SYS_CHROOT,
+	unix.CAP_SYS_PTRACE,
+	unix.CAP_SYS_ADMIN,
+	unix.CAP_SYS_RESOURCE,
+	unix.CAP_SYS_TIME,
+}
+
 var defaultSymLinks = []SymbolicLink{
 	{LinkPath: "/dev/fd", Target: "/proc/self/fd"},
 	{LinkPath: "/dev/stdin", Target: "/proc/self/fd/0"},
 	{LinkPath: "/dev/stdout", Target: "/proc/self/fd/1"},
 	{LinkPath: "/dev/stderr", Target: "/proc/self/fd/2"},
 }
-
-var defaultMaskPaths = []string{
-	// https://github.com/containerd/containerd/blob/f0a32c66dad1e9de716c9960af806105d691cd78/oci/spec.go#L165-L176
-	"/proc/acpi",
-	"/proc/asound",
-	"/proc/kcore",
-	"/proc/keys",
-	"/proc/latency_stats",
-	"/proc/timer_list",
-	"/proc/timer_stats",
-	"/proc/sched_debug",
-	"/sys/firmware",
-	"/proc/scsi",
-
-	"/usr/lib/wsl",
-}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/tobiichi3227/go-sandbox/pkg/unixsocket"
+	"golang.org/x/sys/unix"
 )
 
 func (c *containerServer) handlePing() error {
@@ -18,6 +19,14 @@ func (c *containerServer) handlePing() error {
 func (c *containerServer) handleConf(conf *confCmd) error {
 	if conf != nil {
 		c.containerConfig = conf.Conf
+		// keep the host proc to set time namespace offsets after pivot_root
+		if c.TimeOffsets != nil {
+			fd, err := unix.Open("/proc", unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+			if err != nil {
+				return fmt.Errorf("container: open /proc: %w", err)
+			}
+			c.procFd = fd
+		}
 		if err := initContainer(conf.Conf); err != nil {
 			return err
 		}
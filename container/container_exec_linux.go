@@ -15,6 +15,7 @@ func (c *containerServer) handleExecve(cmd *execCmd, msg unixsocket.Msg) error {
 		files    []uintptr
 		execFile uintptr
 		cgroupFd uintptr
+		landlock uintptr
 		cred     *syscall.Credential
 	)
 	if cmd == nil {
@@ -44,6 +45,14 @@ func (c *containerServer) handleExecve(cmd *execCmd, msg unixsocket.Msg) error {
 		cgroupFd = files[0]
 		files = files[1:]
 	}
+	// if landlock, then the ruleset fd follows
+	if cmd.FdLandlock {
+		if len(files) == 0 {
+			return c.sendErrorReply("handle: expected landlock fd")
+		}
+		landlock = files[0]
+		files = files[1:]
+	}
 
 	var env []string
 	env = append(env, c.defaultEnv...)
@@ -100,6 +109,7 @@ func (c *containerServer) handleExecve(cmd *execCmd, msg unixsocket.Msg) error {
 		Env:        env,
 		ExecFile:   execFile,
 		RLimits:    cmd.RLimits,
+		Sched:      cmd.Sched,
 		Files:      files,
 		WorkDir:    c.WorkDir,
 		NoNewPrivs: true,
@@ -109,9 +119,18 @@ func (c *containerServer) handleExecve(cmd *execCmd, msg unixsocket.Msg) error {
 		CTTY:       cmd.CTTY,
 		Seccomp:    seccomp,
 		CgroupFd:   cgroupFd,
+		LandlockFd: landlock,
+
+		DisableASLR: c.DisableASLR,
+		TimeOffsets: c.TimeOffsets,
+		ProcFd:      uintptr(c.procFd),
 
 		UnshareCgroupAfterSync: c.UnshareCgroup,
 	}
+	// pidfd is -1 if not supported
+	pidFd := -1
+	r.PidFd = &pidFd
+
 	// starts the runner, error is handled same as wait4 to make communication equal
 	pid, err := r.Start()
 	if err != nil {
@@ -125,7 +144,7 @@ func (c *containerServer) handleExecve(cmd *execCmd, msg unixsocket.Msg) error {
 		if err := syncPid(1); err != nil {
 			syscall.Kill(-1, syscall.SIGKILL)
 
-			c.waitPid <- pid
+			c.waitPid <- waitPidCmd{Pid: pid, PidFd: pidFd}
 			ret := <-c.waitPidResult
 			err := c.sendReply(convertReply(ret), unixsocket.Msg{})
 
@@ -134,17 +153,17 @@ func (c *containerServer) handleExecve(cmd *execCmd, msg unixsocket.Msg) error {
 			return err
 		}
 	}
-	return c.handleExecveStarted(pid)
+	return c.handleExecveStarted(pid, pidFd)
 }
 
-func (c *containerServer) handleExecveStarted(pid int) error {
+func (c *containerServer) handleExecveStarted(pid, pidFd int) error {
 	// At this point, either recv kill / send result would be happened
 	// host -> container: kill
 	// container -> host: result
 	// container -> host: done
 
 	// Let's register a wait event
-	c.waitPid <- pid
+	c.waitPid <- waitPidCmd{Pid: pid, PidFd: pidFd}
 
 	var ret waitPidResult
 	select {
@@ -219,10 +238,11 @@ func convertReply(ret waitPidResult) reply {
 		}
 		return reply{
 			ExecReply: &execReply{
-				ExitStatus: int(waitStatus.Signal()),
-				Status:     status,
-				Time:       userTime,
-				Memory:     userMem,
+				ExitStatus:   int(waitStatus.Signal()),
+				Status:       status,
+				Time:         userTime,
+				Memory:       userMem,
+				RuntimeError: runner.ClassifySignal(waitStatus.Signal(), 0),
 			},
 		}
 
@@ -12,13 +12,16 @@ import (
 	"sync"
 	"syscall"
 
+	"github.com/tobiichi3227/go-sandbox/pkg/pidfd"
 	"github.com/tobiichi3227/go-sandbox/pkg/unixsocket"
+	"golang.org/x/sys/unix"
 )
 
 type containerServer struct {
 	socket *socket
 	containerConfig
 	defaultEnv []string
+	procFd     int
 
 	done     chan struct{}
 	err      error
@@ -27,7 +30,7 @@ type containerServer struct {
 	recvCh chan recvCmd
 	sendCh chan sendReply
 
-	waitPid       chan int
+	waitPid       chan waitPidCmd
 	waitPidResult chan waitPidResult
 
 	waitAll     chan struct{}
@@ -45,9 +48,15 @@ type sendReply struct {
 	FileToClose []*os.File
 }
 
+// waitPidCmd waits the process by pidfd if available (PidFd >= 0)
+type waitPidCmd struct {
+	Pid   int
+	PidFd int
+}
+
 type waitPidResult struct {
-	WaitStatus syscall.WaitStatus
-	Rusage     syscall.Rusage
+	WaitStatus unix.WaitStatus
+	Rusage     unix.Rusage
 	Err        error
 }
 
@@ -104,7 +113,7 @@ func Init() (err error) {
 		done:          make(chan struct{}),
 		sendCh:        make(chan sendReply, 1),
 		recvCh:        make(chan recvCmd, 1),
-		waitPid:       make(chan int),
+		waitPid:       make(chan waitPidCmd),
 		waitAll:       make(chan struct{}),
 		waitPidResult: make(chan waitPidResult, 1),
 		waitAllDone:   make(chan struct{}, 1),
@@ -163,13 +172,20 @@ func (c *containerServer) socketError(err error) {
 func (c *containerServer) waitLoop() {
 	for {
 		select {
-		case pid := <-c.waitPid:
-			var waitStatus syscall.WaitStatus
-			var rusage syscall.Rusage
-
-			_, err := syscall.Wait4(pid, &waitStatus, 0, &rusage)
-			for err == syscall.EINTR {
-				_, err = syscall.Wait4(pid, &waitStatus, 0, &rusage)
+		case w := <-c.waitPid:
+			var (
+				waitStatus unix.WaitStatus
+				rusage     unix.Rusage
+				err        error
+			)
+			if w.PidFd >= 0 {
+				waitStatus, err = pidfd.Wait(w.PidFd, &rusage)
+				unix.Close(w.PidFd)
+			} else {
+				_, err = unix.Wait4(w.Pid, &waitStatus, 0, &rusage)
+				for err == unix.EINTR {
+					_, err = unix.Wait4(w.Pid, &waitStatus, 0, &rusage)
+				}
 			}
 			if err != nil {
 				c.waitPidResult <- waitPidResult{
@@ -34,7 +34,7 @@ type Builder struct {
 	SymbolicLinks []SymbolicLink
 
 	// MaskPaths defines paths to be masked to avoid reading information from
-	// outside of the container
+	// outside of the container (default: mount.DefaultMaskPaths)
 	MaskPaths []string
 
 	// WorkDir defines container default work directory (default: /w)
@@ -50,6 +50,11 @@ type Builder struct {
 	// CredGenerator defines a credential generator used to create new container
 	CredGenerator CredGenerator
 
+	// IDMapper writes the uid / gid mappings of the container user namespace
+	// (e.g. idmap.Helper with sub id ranges on non-root hosts), otherwise the
+	// mappings are written directly by the host process
+	IDMapper forkexec.IDMapper
+
 	// Clone flags defines unshare clone flag to create container
 	CloneFlags uintptr
 
@@ -69,6 +74,13 @@ type Builder struct {
 
 	// UnshareCgroupBeforeExec calls unshare cgroup before execution
 	UnshareCgroupBeforeExec bool
+
+	// DisableASLR disables address space randomization for executed programs
+	DisableASLR bool
+
+	// TimeOffsets unshares time namespace with the clock offsets for each
+	// executed programs (kernel >= 5.11 to enter the namespace on execve)
+	TimeOffsets *forkexec.TimeOffsets
 }
 
 // SymbolicLink defines symlinks to be created after mount
@@ -134,6 +146,7 @@ func (b *Builder) Build() (Environment, error) {
 	mounts := b.Mounts
 	if len(mounts) == 0 {
 		mounts = mount.NewDefaultBuilder().
+			WithDevices().        // null, zero, urandom...
 			WithTmpfs("w", "").   // work dir
 			WithTmpfs("tmp", ""). // tmp
 			FilterNotExist().Mounts
@@ -147,7 +160,7 @@ func (b *Builder) Build() (Environment, error) {
 
 	maskPaths := b.MaskPaths
 	if len(maskPaths) == 0 {
-		maskPaths = defaultMaskPaths
+		maskPaths = mount.DefaultMaskPaths
 	}
 
 	// container root directory on the host
@@ -190,6 +203,8 @@ func (b *Builder) Build() (Environment, error) {
 		ContainerUID:  b.ContainerUID,
 		ContainerGID:  b.ContainerGID,
 		UnshareCgroup: b.UnshareCgroupBeforeExec,
+		DisableASLR:   b.DisableASLR,
+		TimeOffsets:   b.TimeOffsets,
 	}); err != nil {
 		c.Destroy()
 		return nil, err
@@ -246,20 +261,36 @@ func (b *Builder) startContainer() (*container, error) {
 		Stderr:     b.Stderr,
 		ExtraFiles: []*os.File{outf},
 		SysProcAttr: &syscall.SysProcAttr{
-			Cloneflags:  cloneFlag,
-			UidMappings: uidMap,
-			GidMappings: gidMap,
-			AmbientCaps: []uintptr{
-				unix.CAP_SYS_ADMIN,
-				unix.CAP_SYS_RESOURCE,
-			},
-			Pdeathsig: syscall.SIGKILL,
+			Cloneflags: cloneFlag,
+			Pdeathsig:  syscall.SIGKILL,
 		},
 	}
+	// the mappings and ambient capabilities only apply to the new user
+	// namespace. The container init executes before the mappings are written
+	// by the mapper, thus it keeps the capabilities needed through ambient set
+	useMapper := b.IDMapper != nil && cloneFlag&unix.CLONE_NEWUSER != 0
+	if useMapper {
+		r.SysProcAttr.AmbientCaps = mapperAmbientCaps
+	} else if cloneFlag&unix.CLONE_NEWUSER != 0 {
+		r.SysProcAttr.UidMappings = uidMap
+		r.SysProcAttr.GidMappings = gidMap
+		r.SysProcAttr.AmbientCaps = []uintptr{
+			unix.CAP_SYS_ADMIN,
+			unix.CAP_SYS_RESOURCE,
+		}
+	}
 	if err = r.Start(); err != nil {
 		ins.Close()
 		return nil, fmt.Errorf("container: failed to start container: %w", err)
 	}
+	if useMapper {
+		if err = b.IDMapper.MapIDs(r.Process.Pid, uidMap, gidMap); err != nil {
+			r.Process.Kill()
+			r.Wait()
+			ins.Close()
+			return nil, fmt.Errorf("container: failed to map ids: %w", err)
+		}
+	}
 	c := &container{
 		process: r.Process,
 		socket:  newSocket(ins),
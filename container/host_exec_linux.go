@@ -3,8 +3,11 @@ package container
 import (
 	"context"
 	"fmt"
+	"syscall"
 	"time"
 
+	"github.com/tobiichi3227/go-sandbox/pkg/forkexec"
+	"github.com/tobiichi3227/go-sandbox/pkg/landlock"
 	"github.com/tobiichi3227/go-sandbox/pkg/rlimit"
 	"github.com/tobiichi3227/go-sandbox/pkg/seccomp"
 	"github.com/tobiichi3227/go-sandbox/pkg/unixsocket"
@@ -31,6 +34,9 @@ type ExecveParam struct {
 	// RLimits specifies POSIX Resource limit through setrlimit
 	RLimits []rlimit.RLimit
 
+	// Sched specifies CPU affinity, scheduling policy and I/O priority
+	Sched *forkexec.Sched
+
 	// Seccomp specifies seccomp filter
 	Seccomp seccomp.Filter
 
@@ -40,9 +46,17 @@ type ExecveParam struct {
 	// SyncFunc calls with pid just before execve (for attach the process to cgroups)
 	SyncFunc func(pid int) error
 
+	// Landlock defines the landlock ruleset applied before seccomp, paths are
+	// resolved in the mount namespace of the host
+	Landlock *landlock.Ruleset
+
 	// SyncAfterExec makes syncFunc sync after the start of the execution
 	// Thus, since pid is not guarantee to be exist (may exit early), it is not passed
 	SyncAfterExec bool
+
+	// FreezeOnLimit freezes the program before kill when the context is
+	// canceled (e.g. time limit hit)
+	FreezeOnLimit *runner.FreezeOnLimit
 }
 
 // Execve runs process inside container. It accepts context cancellation as time limit exceeded.
@@ -60,19 +74,34 @@ func (c *container) Execve(ctx context.Context, param ExecveParam) runner.Result
 	if param.CgroupFD > 0 {
 		files = append(files, int(param.CgroupFD))
 	}
+	// create landlock ruleset on host and send the fd after cgroup fd
+	landlockFd := -1
+	if param.Landlock != nil {
+		fd, err := param.Landlock.Create()
+		if err != nil {
+			return errResult("execve: %v", err)
+		}
+		if fd >= 0 {
+			defer syscall.Close(fd)
+			files = append(files, fd)
+		}
+		landlockFd = fd
+	}
 	files = append(files, uintptrSliceToInt(param.Files)...)
 	msg := unixsocket.Msg{
 		Fds: files,
 	}
 	execCmd := &execCmd{
-		Argv:      param.Args,
-		Env:       param.Env,
-		RLimits:   param.RLimits,
-		Seccomp:   param.Seccomp,
-		FdExec:    param.ExecFile > 0,
-		CTTY:      param.CTTY,
-		SyncAfter: param.SyncAfterExec,
-		FdCgroup:  param.CgroupFD > 0,
+		Argv:       param.Args,
+		Env:        param.Env,
+		RLimits:    param.RLimits,
+		Sched:      param.Sched,
+		Seccomp:    param.Seccomp,
+		FdExec:     param.ExecFile > 0,
+		CTTY:       param.CTTY,
+		SyncAfter:  param.SyncAfterExec,
+		FdCgroup:   param.CgroupFD > 0,
+		FdLandlock: landlockFd >= 0,
 	}
 	cm := cmd{
 		Cmd:     cmdExecve,
@@ -98,6 +127,8 @@ func (c *container) Execve(ctx context.Context, param ExecveParam) runner.Result
 		c.execveSyncKill()
 		return errResult("execve: no pid received")
 	}
+	observer := runner.ObserverFromContext(ctx)
+	observer.OnStart(int(msg.Cred.Pid))
 	if param.SyncFunc != nil {
 		if err := param.SyncFunc(int(msg.Cred.Pid)); err != nil {
 			// tell sync function to exit and recv error
@@ -109,25 +140,37 @@ func (c *container) Execve(ctx context.Context, param ExecveParam) runner.Result
 	if err := c.sendCmd(cmd{Cmd: cmdOk}, unixsocket.Msg{}); err != nil {
 		return errResult("execve: ack failed %v", err)
 	}
+	observer.OnExec()
 
 	// wait for done
-	return c.waitForDone(ctx, sTime)
+	return c.waitForDone(ctx, sTime, param.FreezeOnLimit)
 }
 
-func (c *container) waitForDone(ctx context.Context, sTime time.Time) runner.Result {
+func (c *container) waitForDone(ctx context.Context, sTime time.Time, freeze *runner.FreezeOnLimit) runner.Result {
 	mTime := time.Now()
 	select {
 	case <-c.done: // socket error
 		return convertReplyResult(reply{}, sTime, mTime, c.err)
 
 	case <-ctx.Done(): // cancel
-		c.sendCmd(cmd{Cmd: cmdKill}, unixsocket.Msg{}) // kill
+		if ctx.Err() == context.DeadlineExceeded {
+			runner.ObserverFromContext(ctx).OnLimitHit(runner.StatusTimeLimitExceeded)
+		}
+		freeze.Kill(func() {
+			c.sendCmd(cmd{Cmd: cmdKill}, unixsocket.Msg{}) // kill
+		})
 		reply, _, err := c.recvReply()
 		return convertReplyResult(reply, sTime, mTime, err)
 
 	case ret := <-c.recvCh: // result
 		err := c.sendCmd(cmd{Cmd: cmdKill}, unixsocket.Msg{}) // kill
-		return convertReplyResult(ret.Reply, sTime, mTime, err)
+		result := convertReplyResult(ret.Reply, sTime, mTime, err)
+		// limit hit detected by the container after exit
+		switch result.Status {
+		case runner.StatusTimeLimitExceeded, runner.StatusMemoryLimitExceeded, runner.StatusOutputLimitExceeded:
+			runner.ObserverFromContext(ctx).OnLimitHit(result.Status)
+		}
+		return result
 	}
 }
 
@@ -153,12 +196,13 @@ func convertReplyResult(reply reply, sTime, mTime time.Time, err error) runner.R
 	}
 	// emit result after all communication finish
 	return runner.Result{
-		Status:      reply.ExecReply.Status,
-		ExitStatus:  reply.ExecReply.ExitStatus,
-		Time:        reply.ExecReply.Time,
-		Memory:      reply.ExecReply.Memory,
-		SetUpTime:   mTime.Sub(sTime),
-		RunningTime: time.Since(mTime),
+		Status:       reply.ExecReply.Status,
+		ExitStatus:   reply.ExecReply.ExitStatus,
+		RuntimeError: reply.ExecReply.RuntimeError,
+		Time:         reply.ExecReply.Time,
+		Memory:       reply.ExecReply.Memory,
+		SetUpTime:    mTime.Sub(sTime),
+		RunningTime:  time.Since(mTime),
 	}
 }
 
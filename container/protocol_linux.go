@@ -5,6 +5,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/tobiichi3227/go-sandbox/pkg/forkexec"
 	"github.com/tobiichi3227/go-sandbox/pkg/mount"
 	"github.com/tobiichi3227/go-sandbox/pkg/rlimit"
 	"github.com/tobiichi3227/go-sandbox/pkg/seccomp"
@@ -36,14 +37,16 @@ type deleteCmd struct {
 
 // execCmd stores execve parameter
 type execCmd struct {
-	Argv      []string        // execve argv
-	Env       []string        // execve env
-	RLimits   []rlimit.RLimit // execve posix rlimit
-	Seccomp   seccomp.Filter  // seccomp filter
-	FdExec    bool            // if use fexecve (fd[0] as exec)
-	FdCgroup  bool            // if use cgroupFd
-	CTTY      bool            // if set CTTY
-	SyncAfter bool            // if sync function calls after execve returns
+	Argv       []string        // execve argv
+	Env        []string        // execve env
+	RLimits    []rlimit.RLimit // execve posix rlimit
+	Sched      *forkexec.Sched // execve cpu affinity & scheduling
+	Seccomp    seccomp.Filter  // seccomp filter
+	FdExec     bool            // if use fexecve (fd[0] as exec)
+	FdCgroup   bool            // if use cgroupFd
+	FdLandlock bool            // if use landlock ruleset fd
+	CTTY       bool            // if set CTTY
+	SyncAfter  bool            // if sync function calls after execve returns
 }
 
 // confCmd stores conf parameter
@@ -68,6 +71,8 @@ type containerConfig struct {
 	ContainerGID  int
 	Cred          bool
 	UnshareCgroup bool
+	DisableASLR   bool
+	TimeOffsets   *forkexec.TimeOffsets
 }
 
 // reply is the reply message send back to controller
@@ -84,10 +89,11 @@ type errorReply struct {
 
 // execReply stores execve result
 type execReply struct {
-	ExitStatus int           // waitpid exit status
-	Status     runner.Status // return status
-	Time       time.Duration // waitpid user CPU (ns)
-	Memory     runner.Size   // waitpid user memory (byte)
+	ExitStatus   int                 // waitpid exit status
+	Status       runner.Status       // return status
+	Time         time.Duration       // waitpid user CPU (ns)
+	Memory       runner.Size         // waitpid user memory (byte)
+	RuntimeError runner.RuntimeError // classified cause if signalled
 }
 
 func (e *errorReply) Error() string {
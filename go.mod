@@ -3,7 +3,9 @@ module github.com/tobiichi3227/go-sandbox
 go 1.24
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/elastic/go-seccomp-bpf v1.6.0
 	golang.org/x/net v0.43.0
 	golang.org/x/sys v0.35.0
+	gopkg.in/yaml.v3 v3.0.1
 )
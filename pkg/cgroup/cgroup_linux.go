@@ -54,6 +54,16 @@ type Cgroup interface {
 	// Processes lists all existing process pid from the cgroup
 	Processes() ([]int, error)
 
+	// Freeze stops all processes in the cgroup and waits until frozen
+	Freeze() error
+
+	// Thaw resumes the frozen processes in the cgroup
+	Thaw() error
+
+	// Kill kills all processes in the cgroup, including those escaped from the
+	// process group by setsid or re-parenting
+	Kill() error
+
 	// New creates a sub-cgroup based on the existing one
 	New(string) (Cgroup, error)
 
@@ -86,6 +96,7 @@ func loopV1Controllers(ct *Controllers, v1 *V1, f func(string, **v1controller) e
 		{ct.CPUAcct, CPUAcct, &v1.cpuacct},
 		{ct.Memory, Memory, &v1.memory},
 		{ct.Pids, Pids, &v1.pids},
+		{ct.Freezer, Freezer, &v1.freezer},
 	} {
 		if !c.available {
 			continue
@@ -10,6 +10,10 @@ const (
 
 	cgroupSubtreeControl = "cgroup.subtree_control"
 	cgroupControllers    = "cgroup.controllers"
+	cgroupKill           = "cgroup.kill"
+	cgroupFreeze         = "cgroup.freeze"
+	cgroupEvents         = "cgroup.events"
+	freezerState         = "freezer.state"
 
 	filePerm = 0644
 	dirPerm  = 0755
@@ -19,6 +23,7 @@ const (
 	CPUSet  = "cpuset"
 	Memory  = "memory"
 	Pids    = "pids"
+	Freezer = "freezer"
 )
 
 // Type defines the version of cgroup
@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"golang.org/x/sys/unix"
 )
@@ -123,6 +124,13 @@ func remove(name string) error {
 	return nil
 }
 
+const (
+	pollCount    = 1000
+	pollInterval = time.Millisecond
+)
+
+var errPollTimeout = errors.New("cgroup: wait for cgroup state timeout")
+
 var errPatternHasSeparator = errors.New("pattern contains path separator")
 
 // prefixAndSuffix splits pattern by the last wildcard "*", if applicable,
@@ -157,6 +165,53 @@ func writeFile(p string, content []byte, perm fs.FileMode) error {
 	return err
 }
 
+// killProcesses sends SIGKILL to all processes listed
+func killProcesses(procs func() ([]int, error)) error {
+	pids, err := procs()
+	if err != nil {
+		return err
+	}
+	for _, p := range pids {
+		// empty line in cgroup.procs
+		if p == 0 {
+			continue
+		}
+		if err := unix.Kill(p, unix.SIGKILL); err != nil && err != unix.ESRCH {
+			return err
+		}
+	}
+	return nil
+}
+
+// noProcesses returns a check that no process listed
+func noProcesses(procs func() ([]int, error)) func() (bool, error) {
+	return func() (bool, error) {
+		pids, err := procs()
+		if err != nil {
+			return false, err
+		}
+		for _, p := range pids {
+			if p != 0 {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
+
+// pollUntil calls f until it returns true or error, or returns
+// errPollTimeout after pollCount attempts
+func pollUntil(f func() (bool, error)) error {
+	for range pollCount {
+		ok, err := f()
+		if err != nil || ok {
+			return err
+		}
+		time.Sleep(pollInterval)
+	}
+	return errPollTimeout
+}
+
 func nextRandom() string {
 	return strconv.Itoa(int(rand.Int32()))
 }
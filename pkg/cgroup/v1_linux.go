@@ -20,6 +20,7 @@ type V1 struct {
 	cpuacct *v1controller
 	memory  *v1controller
 	pids    *v1controller
+	freezer *v1controller
 
 	all []*v1controller
 
@@ -41,6 +42,7 @@ func (c *V1) String() string {
 		{c.cpuacct, CPUAcct},
 		{c.memory, Memory},
 		{c.pids, Pids},
+		{c.freezer, Freezer},
 	} {
 		if v.now == nil {
 			continue
@@ -89,6 +91,7 @@ func (c *V1) New(name string) (cg Cgroup, err error) {
 		{c.cpuacct, &v1.cpuacct},
 		{c.memory, &v1.memory},
 		{c.pids, &v1.pids},
+		{c.freezer, &v1.freezer},
 	} {
 		if v.now == nil {
 			continue
@@ -211,6 +214,58 @@ func (c *V1) OOMEventCount() (uint64, error) {
 	return 0, fmt.Errorf("oom_kill not found in memory.oom_control")
 }
 
+// Kill freezes the cgroup by freezer controller and sends SIGKILL to all
+// processes inside. Without the freezer controller, it repeats the kill until
+// no process left so that newly forked processes are killed as well. It waits
+// until all processes exited
+func (c *V1) Kill() error {
+	if c.freezer == nil {
+		return pollUntil(func() (bool, error) {
+			if err := killProcesses(c.Processes); err != nil {
+				return false, err
+			}
+			return noProcesses(c.Processes)()
+		})
+	}
+	if err := c.freezeAndKill(); err != nil {
+		return err
+	}
+	return pollUntil(noProcesses(c.Processes))
+}
+
+func (c *V1) freezeAndKill() error {
+	if err := c.Freeze(); err != nil {
+		return err
+	}
+	// frozen processes handle SIGKILL after thaw
+	defer c.Thaw()
+	return killProcesses(func() ([]int, error) {
+		return ReadProcesses(filepath.Join(c.freezer.path, cgroupProcs))
+	})
+}
+
+// Freeze writes freezer.state and waits until the cgroup is frozen
+func (c *V1) Freeze() error {
+	if c.freezer == nil {
+		return ErrNotInitialized
+	}
+	if err := c.freezer.WriteFile(freezerState, []byte("FROZEN")); err != nil {
+		return err
+	}
+	return pollUntil(func() (bool, error) {
+		b, err := c.freezer.ReadFile(freezerState)
+		return strings.TrimSpace(string(b)) == "FROZEN", err
+	})
+}
+
+// Thaw writes freezer.state to resume the frozen cgroup
+func (c *V1) Thaw() error {
+	if c.freezer == nil {
+		return ErrNotInitialized
+	}
+	return c.freezer.WriteFile(freezerState, []byte("THAWED"))
+}
+
 // SetMemoryLimit write memory.limit_in_bytes
 func (c *V1) SetMemoryLimit(i uint64) error {
 	return c.memory.WriteUint("memory.limit_in_bytes", i)
@@ -3,6 +3,7 @@ package cgroup
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -188,6 +189,53 @@ func (c *V2) OOMEventCount() (uint64, error) {
 	return 0, os.ErrNotExist
 }
 
+// Kill writes cgroup.kill (kernel >= 5.14) to kill all processes in the
+// cgroup and its descendants. For older kernel, it freezes the cgroup and
+// sends SIGKILL to all processes inside. It waits until all processes exited
+func (c *V2) Kill() error {
+	err := c.WriteFile(cgroupKill, []byte("1"))
+	if errors.Is(err, os.ErrNotExist) {
+		err = c.freezeAndKill()
+	}
+	if err != nil {
+		return err
+	}
+	return pollUntil(noProcesses(c.Processes))
+}
+
+func (c *V2) freezeAndKill() error {
+	if err := c.Freeze(); err != nil {
+		return err
+	}
+	defer c.Thaw()
+	return killProcesses(c.Processes)
+}
+
+// Freeze writes cgroup.freeze and waits until the cgroup is frozen
+func (c *V2) Freeze() error {
+	if err := c.WriteFile(cgroupFreeze, []byte("1")); err != nil {
+		return err
+	}
+	return pollUntil(func() (bool, error) {
+		b, err := c.ReadFile(cgroupEvents)
+		if err != nil {
+			return false, err
+		}
+		for _, line := range strings.Split(string(b), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) >= 2 && fields[0] == "frozen" {
+				return fields[1] == "1", nil
+			}
+		}
+		return false, os.ErrNotExist
+	})
+}
+
+// Thaw writes cgroup.freeze to resume the frozen cgroup
+func (c *V2) Thaw() error {
+	return c.WriteFile(cgroupFreeze, []byte("0"))
+}
+
 // SetCPUBandwidth set cpu.max quota period
 func (c *V2) SetCPUBandwidth(quota, period uint64) error {
 	if !c.control.CPU {
@@ -16,6 +16,9 @@ const (
 
 	// Read-only bind mount need to be remounted
 	bindRo = unix.MS_BIND | unix.MS_RDONLY
+
+	// personality flag to disable ASLR (not defined in unix package)
+	_ADDR_NO_RANDOMIZE = 0x0040000
 )
 
 // used by unshare remount / to private
@@ -29,6 +32,10 @@ var (
 	// tmp dir made by pivot_root
 	oldRoot = []byte("old_root\000")
 
+	// clock offsets for unshare time
+	timensOffsets    = []byte("/proc/self/timens_offsets\000")
+	timensOffsetsRel = []byte("self/timens_offsets\000")
+
 	// set groups for unshare user
 	setGIDAllow = []byte("allow")
 	setGIDDeny  = []byte("deny")
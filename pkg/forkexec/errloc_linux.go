@@ -21,6 +21,7 @@ const (
 	LocCloseWrite
 	LocUnshareUserRead
 	LocGetPid
+	LocTimeNamespace
 	LocKeepCapability
 	LocSetGroups
 	LocSetGid
@@ -34,6 +35,9 @@ const (
 	LocMountChdir
 	LocReMount
 	LocMount
+	LocOpenTree
+	LocMountSetAttr
+	LocMoveMount
 	LocMountMkdir
 	LocPivotRoot
 	LocUmount
@@ -41,7 +45,12 @@ const (
 	LocMountRootReadonly
 	LocChdir
 	LocSetRlimit
+	LocPersonality
+	LocSetAffinity
+	LocSetScheduler
+	LocSetIOPrio
 	LocSetNoNewPrivs
+	LocLandlock
 	LocDropCapability
 	LocSetCap
 	LocPtraceMe
@@ -58,6 +67,7 @@ var locToString = []string{
 	"close_write",
 	"unshare_user_read",
 	"getpid",
+	"time_namespace",
 	"keep_capability",
 	"setgroups",
 	"setgid",
@@ -71,6 +81,9 @@ var locToString = []string{
 	"mount(chdir)",
 	"mount(remount)",
 	"mount",
+	"open_tree",
+	"mount_setattr",
+	"move_mount",
 	"mount(mkdir)",
 	"pivot_root",
 	"umount",
@@ -78,7 +91,12 @@ var locToString = []string{
 	"mount(readonly)",
 	"chdir",
 	"setrlimt",
+	"personality",
+	"sched_setaffinity",
+	"sched_setscheduler",
+	"ioprio_set",
 	"set_no_new_privs",
+	"landlock",
 	"drop_capability",
 	"set_cap",
 	"ptrace_me",
@@ -15,7 +15,7 @@ import (
 //go:noinline
 //go:norace
 //go:nocheckptr
-func forkAndExecInChild(r *Runner, argv0 *byte, argv, env []*byte, workdir, hostname, domainname, pivotRoot *byte, p [2]int) (r1 uintptr, err1 syscall.Errno) {
+func forkAndExecInChild(r *Runner, argv0 *byte, argv, env []*byte, workdir, hostname, domainname, pivotRoot *byte, timeOffsets []byte, cpuSet *unix.CPUSet, landlockFd int, p [2]int) (r1 uintptr, err1 syscall.Errno) {
 	var (
 		clone3      *cloneArgs
 		pid         uintptr
@@ -23,6 +23,7 @@ func forkAndExecInChild(r *Runner, argv0 *byte, argv, env []*byte, workdir, host
 		unshareUser = r.CloneFlags&unix.CLONE_NEWUSER == unix.CLONE_NEWUSER
 		i           int
 		rlim        rlimit.RLimit
+		pidFd       int32 = -1
 	)
 	pipe := p[1]
 
@@ -34,12 +35,19 @@ func forkAndExecInChild(r *Runner, argv0 *byte, argv, env []*byte, workdir, host
 		flag |= syscall.CLONE_VM | syscall.CLONE_VFORK
 	}
 
-	// use clone3 if cgroupFd specified
-	if r.CgroupFd > 0 {
+	// use clone3 if cgroupFd or pidFd specified
+	if r.CgroupFd > 0 || r.PidFd != nil {
 		clone3 = &cloneArgs{
-			flags:      uint64(flag) | unix.CLONE_INTO_CGROUP,
+			flags:      uint64(flag),
 			exitSignal: uint64(syscall.SIGCHLD),
-			cgroup:     uint64(r.CgroupFd),
+		}
+		if r.CgroupFd > 0 {
+			clone3.flags |= unix.CLONE_INTO_CGROUP
+			clone3.cgroup = uint64(r.CgroupFd)
+		}
+		if r.PidFd != nil {
+			clone3.flags |= unix.CLONE_PIDFD
+			clone3.pidFD = uint64(uintptr(unsafe.Pointer(&pidFd)))
 		}
 	}
 	flag |= uintptr(syscall.SIGCHLD)
@@ -56,7 +64,12 @@ func forkAndExecInChild(r *Runner, argv0 *byte, argv, env []*byte, workdir, host
 	// UnshareFlags (new namespaces) is activated by clone syscall
 	if clone3 != nil {
 		r1, err1 = vfork.RawVforkSyscall(unix.SYS_CLONE3, uintptr(unsafe.Pointer(clone3)), unsafe.Sizeof(*clone3), 0)
-	} else {
+		// fallback to clone without pidfd if clone3 is not supported
+		if err1 == syscall.ENOSYS && r.CgroupFd == 0 {
+			clone3 = nil
+		}
+	}
+	if clone3 == nil {
 		if runtime.GOARCH == "s390x" {
 			// On Linux/s390, the first two arguments of clone(2) are swapped.
 			r1, err1 = vfork.RawVforkSyscall(syscall.SYS_CLONE, 0, flag, 0)
@@ -66,6 +79,9 @@ func forkAndExecInChild(r *Runner, argv0 *byte, argv, env []*byte, workdir, host
 	}
 	if err1 != 0 || r1 != 0 {
 		// in parent process, immediate return
+		if r.PidFd != nil {
+			*r.PidFd = int(pidFd)
+		}
 		return
 	}
 
@@ -104,6 +120,30 @@ func forkAndExecInChild(r *Runner, argv0 *byte, argv, env []*byte, workdir, host
 		childExitError(pipe, LocGetPid, err1)
 	}
 
+	// unshare time namespace and set clock offsets before any process enters
+	if r.TimeOffsets != nil {
+		_, _, err1 = syscall.RawSyscall(syscall.SYS_UNSHARE, uintptr(unix.CLONE_NEWTIME), 0, 0)
+		if err1 != 0 {
+			childExitError(pipe, LocTimeNamespace, err1)
+		}
+		if len(timeOffsets) > 0 {
+			dirfd, path := uintptr(_AT_FDCWD), &timensOffsets[0]
+			if r.ProcFd > 0 {
+				dirfd, path = r.ProcFd, &timensOffsetsRel[0]
+			}
+			r1, _, err1 = syscall.RawSyscall6(syscall.SYS_OPENAT, dirfd, uintptr(unsafe.Pointer(path)),
+				uintptr(syscall.O_WRONLY|syscall.O_CLOEXEC), 0, 0, 0)
+			if err1 != 0 {
+				childExitError(pipe, LocTimeNamespace, err1)
+			}
+			_, _, err1 = syscall.RawSyscall(syscall.SYS_WRITE, r1, uintptr(unsafe.Pointer(&timeOffsets[0])), uintptr(len(timeOffsets)))
+			if err1 != 0 {
+				childExitError(pipe, LocTimeNamespace, err1)
+			}
+			syscall.RawSyscall(syscall.SYS_CLOSE, r1, 0, 0)
+		}
+	}
+
 	// keep capabilities through set_uid / set_gid calls (make sure we can use unshare cgroup), later dropped
 	if r.Credential != nil || r.UnshareCgroupAfterSync {
 		_, _, err1 = syscall.RawSyscall(syscall.SYS_PRCTL, syscall.PR_SET_SECUREBITS,
@@ -158,10 +198,22 @@ func forkAndExecInChild(r *Runner, argv0 *byte, argv, env []*byte, workdir, host
 		r.ExecFile = uintptr(nextfd)
 		nextfd++
 	}
+	if landlockFd >= 0 && landlockFd < nextfd {
+		// Avoid fd rewrite
+		for nextfd == pipe || (r.ExecFile > 0 && nextfd == int(r.ExecFile)) {
+			nextfd++
+		}
+		_, _, err1 = syscall.RawSyscall(syscall.SYS_DUP3, uintptr(landlockFd), uintptr(nextfd), syscall.O_CLOEXEC)
+		if err1 != 0 {
+			childExitError(pipe, LocDup3, err1)
+		}
+		landlockFd = nextfd
+		nextfd++
+	}
 	for i = 0; i < len(fd); i++ {
 		if fd[i] >= 0 && fd[i] < int(i) {
 			// Avoid fd rewrite
-			for nextfd == pipe || (r.ExecFile > 0 && nextfd == int(r.ExecFile)) {
+			for nextfd == pipe || (r.ExecFile > 0 && nextfd == int(r.ExecFile)) || nextfd == landlockFd {
 				nextfd++
 			}
 			_, _, err1 = syscall.RawSyscall(syscall.SYS_DUP3, uintptr(fd[i]), uintptr(nextfd), syscall.O_CLOEXEC)
@@ -236,6 +288,7 @@ func forkAndExecInChild(r *Runner, argv0 *byte, argv, env []*byte, workdir, host
 		}
 
 		// performing mounts
+		var attr unix.MountAttr
 		for i, m := range r.Mounts {
 			// mkdirs(target)
 			for j, p := range m.Prefixes {
@@ -252,6 +305,41 @@ func forkAndExecInChild(r *Runner, argv0 *byte, argv, env []*byte, workdir, host
 					childExitErrorWithIndex(pipe, LocMountMkdir, i, err1)
 				}
 			}
+			// idmapped mount: open_tree(source) -> mount_setattr(idmap) -> move_mount(target)
+			if m.UserNs != nil {
+				flag := uintptr(unix.OPEN_TREE_CLONE | unix.OPEN_TREE_CLOEXEC)
+				if m.Flags&syscall.MS_REC == syscall.MS_REC {
+					flag |= unix.AT_RECURSIVE
+				}
+				r1, _, err1 = syscall.RawSyscall(unix.SYS_OPEN_TREE, uintptr(_AT_FDCWD), uintptr(unsafe.Pointer(m.Source)), flag)
+				if err1 != 0 {
+					childExitErrorWithIndex(pipe, LocOpenTree, i, err1)
+				}
+				tree := r1
+				r1, _, err1 = syscall.RawSyscall6(syscall.SYS_OPENAT, uintptr(_AT_FDCWD), uintptr(unsafe.Pointer(m.UserNs)),
+					uintptr(syscall.O_RDONLY|syscall.O_CLOEXEC), 0, 0, 0)
+				if err1 != 0 {
+					childExitErrorWithIndex(pipe, LocMountSetAttr, i, err1)
+				}
+				attr = unix.MountAttr{Attr_set: m.Attr | unix.MOUNT_ATTR_IDMAP, Userns_fd: uint64(r1)}
+				flag = unix.AT_EMPTY_PATH
+				if m.Recursive {
+					flag |= unix.AT_RECURSIVE
+				}
+				_, _, err1 = syscall.RawSyscall6(unix.SYS_MOUNT_SETATTR, tree, uintptr(unsafe.Pointer(&empty[0])), flag,
+					uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+				if err1 != 0 {
+					childExitErrorWithIndex(pipe, LocMountSetAttr, i, err1)
+				}
+				syscall.RawSyscall(syscall.SYS_CLOSE, r1, 0, 0)
+				_, _, err1 = syscall.RawSyscall6(unix.SYS_MOVE_MOUNT, tree, uintptr(unsafe.Pointer(&empty[0])),
+					uintptr(_AT_FDCWD), uintptr(unsafe.Pointer(m.Target)), unix.MOVE_MOUNT_F_EMPTY_PATH, 0)
+				if err1 != 0 {
+					childExitErrorWithIndex(pipe, LocMoveMount, i, err1)
+				}
+				syscall.RawSyscall(syscall.SYS_CLOSE, tree, 0, 0)
+				continue
+			}
 			// mount(source, target, fsType, flags, data)
 			_, _, err1 = syscall.RawSyscall6(syscall.SYS_MOUNT, uintptr(unsafe.Pointer(m.Source)),
 				uintptr(unsafe.Pointer(m.Target)), uintptr(unsafe.Pointer(m.FsType)), uintptr(m.Flags),
@@ -276,6 +364,19 @@ func forkAndExecInChild(r *Runner, argv0 *byte, argv, env []*byte, workdir, host
 					childExitErrorWithIndex(pipe, LocReMount, i, err1)
 				}
 			}
+			// mount_setattr(AT_FDCWD, target, flags, attr)
+			if m.Attr != 0 {
+				attr = unix.MountAttr{Attr_set: m.Attr}
+				var flag uintptr
+				if m.Recursive {
+					flag = unix.AT_RECURSIVE
+				}
+				_, _, err1 = syscall.RawSyscall6(unix.SYS_MOUNT_SETATTR, uintptr(_AT_FDCWD), uintptr(unsafe.Pointer(m.Target)), flag,
+					uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+				if err1 != 0 {
+					childExitErrorWithIndex(pipe, LocMountSetAttr, i, err1)
+				}
+			}
 		}
 
 		// pivot_root
@@ -363,14 +464,61 @@ func forkAndExecInChild(r *Runner, argv0 *byte, argv, env []*byte, workdir, host
 		}
 	}
 
+	// Disable ASLR
+	if r.DisableASLR {
+		r1, _, err1 = syscall.RawSyscall(syscall.SYS_PERSONALITY, 0xffffffff, 0, 0)
+		if err1 == 0 {
+			_, _, err1 = syscall.RawSyscall(syscall.SYS_PERSONALITY, r1|_ADDR_NO_RANDOMIZE, 0, 0)
+		}
+		if err1 != 0 {
+			childExitError(pipe, LocPersonality, err1)
+		}
+	}
+
+	// Set scheduling
+	if cpuSet != nil {
+		_, _, err1 = syscall.RawSyscall(unix.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(*cpuSet), uintptr(unsafe.Pointer(cpuSet)))
+		if err1 != 0 {
+			childExitError(pipe, LocSetAffinity, err1)
+		}
+	}
+	if s := r.Sched; s != nil {
+		if s.Policy != 0 || s.Nice != 0 {
+			var param int32
+			_, _, err1 = syscall.RawSyscall(unix.SYS_SCHED_SETSCHEDULER, 0, uintptr(s.Policy), uintptr(unsafe.Pointer(&param)))
+			if err1 != 0 {
+				childExitError(pipe, LocSetScheduler, err1)
+			}
+			_, _, err1 = syscall.RawSyscall(syscall.SYS_SETPRIORITY, syscall.PRIO_PROCESS, 0, uintptr(s.Nice))
+			if err1 != 0 {
+				childExitError(pipe, LocSetScheduler, err1)
+			}
+		}
+		if s.IOClass != 0 {
+			_, _, err1 = syscall.RawSyscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, 0, uintptr(s.IOClass<<ioprioClassShift|s.IOLevel))
+			if err1 != 0 {
+				childExitError(pipe, LocSetIOPrio, err1)
+			}
+		}
+	}
+
 	// No new privs
-	if r.NoNewPrivs || r.Seccomp != nil {
+	if r.NoNewPrivs || r.Seccomp != nil || landlockFd >= 0 {
 		_, _, err1 = syscall.RawSyscall6(syscall.SYS_PRCTL, unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0, 0)
 		if err1 != 0 {
 			childExitError(pipe, LocSetNoNewPrivs, err1)
 		}
 	}
 
+	// Landlock restrict self after mounts (mount is denied by landlock)
+	if landlockFd >= 0 {
+		_, _, err1 = syscall.RawSyscall(unix.SYS_LANDLOCK_RESTRICT_SELF, uintptr(landlockFd), 0, 0)
+		if err1 != 0 {
+			childExitError(pipe, LocLandlock, err1)
+		}
+		syscall.RawSyscall(syscall.SYS_CLOSE, uintptr(landlockFd), 0, 0)
+	}
+
 	// Drop all capabilities
 	if (r.Credential != nil || r.DropCaps) && !r.UnshareCgroupAfterSync {
 		// make sure the children have no privilege at all
@@ -1,6 +1,8 @@
 package forkexec
 
 import (
+	"errors"
+	"fmt"
 	"syscall"
 	"unsafe" // required for go:linkname.
 
@@ -41,6 +43,28 @@ func (r *Runner) Start() (int, error) {
 		return 0, err
 	}
 
+	// prepare time namespace offsets
+	timeOffsets := r.TimeOffsets.content()
+
+	// prepare cpu affinity mask
+	cpuSet, err := r.Sched.cpuSet()
+	if err != nil {
+		return 0, err
+	}
+
+	// create landlock ruleset, child restricts itself with the fd
+	landlockFd := -1
+	if r.Landlock != nil {
+		if landlockFd, err = r.Landlock.Create(); err != nil {
+			return 0, err
+		}
+		if landlockFd >= 0 {
+			defer unix.Close(landlockFd)
+		}
+	} else if r.LandlockFd > 0 {
+		landlockFd = int(r.LandlockFd)
+	}
+
 	// socketpair p used to notify child the uid / gid mapping have been setup
 	// socketpair p is also used to sync with parent before final execve
 	// p[0] is used by parent and p[1] is used by child
@@ -50,13 +74,18 @@ func (r *Runner) Start() (int, error) {
 	}
 
 	// fork in child
-	pid, err1 := forkAndExecInChild(r, argv0, argv, env, workdir, hostname, domainname, pivotRoot, p)
+	pid, err1 := forkAndExecInChild(r, argv0, argv, env, workdir, hostname, domainname, pivotRoot, timeOffsets, cpuSet, landlockFd, p)
 
 	// restore all signals
 	afterFork()
 	syscall.ForkLock.Unlock()
 
-	return syncWithChild(r, p, int(pid), err1)
+	pid1, err := syncWithChild(r, p, int(pid), err1)
+	if err != nil && r.PidFd != nil && *r.PidFd >= 0 {
+		unix.Close(*r.PidFd)
+		*r.PidFd = -1
+	}
+	return pid1, err
 }
 
 func syncWithChild(r *Runner, p [2]int, pid int, err1 syscall.Errno) (int, error) {
@@ -81,10 +110,16 @@ func syncWithChild(r *Runner, p [2]int, pid int, err1 syscall.Errno) (int, error
 
 	// synchronize with child for uid / gid map
 	if unshareUser {
-		if err = writeIDMaps(r, int(pid)); err != nil {
-			err2 = err.(syscall.Errno)
+		if err = r.mapIDs(int(pid)); err != nil {
+			err2 = syscall.EPERM
+			errors.As(err, &err2)
 		}
 		syscall.RawSyscall(syscall.SYS_WRITE, uintptr(p[0]), uintptr(unsafe.Pointer(&err2)), uintptr(unsafe.Sizeof(err2)))
+		// report the error from IDMapper directly
+		if _, ok := err.(syscall.Errno); err != nil && !ok {
+			err = fmt.Errorf("forkexec: map ids: %w", err)
+			goto fail
+		}
 	}
 
 	// if syncfunc return error, then fail child immediately
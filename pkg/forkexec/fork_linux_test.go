@@ -2,10 +2,18 @@ package forkexec
 
 import (
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"testing"
+	"time"
 
+	"golang.org/x/sys/unix"
+
+	"github.com/tobiichi3227/go-sandbox/pkg/landlock"
 	"github.com/tobiichi3227/go-sandbox/pkg/mount"
+	"github.com/tobiichi3227/go-sandbox/pkg/pidfd"
 )
 
 func TestFork_DropCaps(t *testing.T) {
@@ -117,3 +125,376 @@ func TestFork_ENOENT(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestFork_PidFd(t *testing.T) {
+	t.Parallel()
+	pidFd := -1
+	r := Runner{
+		Args:  []string{"/bin/false"},
+		PidFd: &pidFd,
+	}
+	pid, err := r.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pidFd < 0 {
+		syscall.Wait4(pid, nil, 0, nil)
+		t.Skip("clone3 with CLONE_PIDFD is not supported")
+	}
+	defer syscall.Close(pidFd)
+
+	ws, err := pidfd.Wait(pidFd, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ws.Exited() || ws.ExitStatus() != 1 {
+		t.Errorf("wait status = %#x, expected exit status 1", ws)
+	}
+}
+
+func TestFork_Landlock(t *testing.T) {
+	t.Parallel()
+	if landlock.ABI() == 0 {
+		t.Skip("landlock is not supported")
+	}
+	dir := t.TempDir()
+	f := filepath.Join(dir, "file")
+	if err := os.WriteFile(f, []byte("test"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+
+	var sys []string
+	for _, p := range []string{"/bin", "/usr", "/lib", "/lib64", "/etc"} {
+		if _, err := os.Stat(p); err == nil {
+			sys = append(sys, p)
+		}
+	}
+	tests := []struct {
+		name   string
+		paths  []string
+		status int
+	}{
+		{"allowed", []string{dir}, 0},
+		{"denied", nil, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rs := (&landlock.Ruleset{}).
+				WithPath(landlock.AccessReadExec, sys...).
+				WithPath(landlock.AccessRead, tc.paths...)
+			r := Runner{
+				Args:     []string{"/bin/cat", f},
+				Files:    []uintptr{0, null.Fd(), null.Fd()},
+				Landlock: rs,
+			}
+			pid, err := r.Start()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ws syscall.WaitStatus
+			if _, err := syscall.Wait4(pid, &ws, 0, nil); err != nil {
+				t.Fatal(err)
+			}
+			if !ws.Exited() || ws.ExitStatus() != tc.status {
+				t.Errorf("wait status = %#x, expected exit status %d", ws, tc.status)
+			}
+		})
+	}
+}
+
+func TestFork_Sched(t *testing.T) {
+	t.Parallel()
+	out, err := os.CreateTemp(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	r := Runner{
+		Args:  []string{"/bin/sh", "-c", "cat /proc/self/stat; grep Cpus_allowed_list /proc/self/status"},
+		Env:   []string{"PATH=/usr/bin:/bin"},
+		Files: []uintptr{0, out.Fd(), 2},
+		Sched: &Sched{
+			CPUs:    []int{0},
+			Policy:  unix.SCHED_BATCH,
+			Nice:    5,
+			IOClass: IOPrioClassBE,
+			IOLevel: 7,
+		},
+	}
+	pid, err := r.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ws syscall.WaitStatus
+	if _, err := syscall.Wait4(pid, &ws, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !ws.Exited() || ws.ExitStatus() != 0 {
+		t.Fatalf("wait status = %#x", ws)
+	}
+	b, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat, status, _ := strings.Cut(string(b), "\n")
+	// fields after comm starts from state (3)
+	fields := strings.Fields(stat[strings.LastIndexByte(stat, ')')+1:])
+	if len(fields) < 39 {
+		t.Fatalf("invalid stat: %s", stat)
+	}
+	if nice := fields[19-3]; nice != "5" {
+		t.Errorf("nice = %s, expected 5", nice)
+	}
+	if policy := fields[41-3]; policy != strconv.Itoa(unix.SCHED_BATCH) {
+		t.Errorf("policy = %s, expected %d", policy, unix.SCHED_BATCH)
+	}
+	if !strings.Contains(status, "Cpus_allowed_list:\t0\n") {
+		t.Errorf("unexpected cpus allowed: %s", status)
+	}
+}
+
+func TestFork_SchedInvalidCPU(t *testing.T) {
+	t.Parallel()
+	r := Runner{
+		Args:  []string{"/bin/echo"},
+		Sched: &Sched{CPUs: []int{-1}},
+	}
+	if _, err := r.Start(); err == nil {
+		t.Fatal("expected error for invalid cpu")
+	}
+}
+
+func TestFork_Deterministic(t *testing.T) {
+	t.Parallel()
+	if _, err := os.Stat("/proc/self/timens_offsets"); err != nil {
+		t.Skip("time namespace is not supported")
+	}
+	out, err := os.CreateTemp(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	const boottime = 1000000 * time.Second
+	r := Runner{
+		Args:        []string{"/bin/sh", "-c", "cat /proc/self/personality /proc/uptime"},
+		Env:         []string{"PATH=/usr/bin:/bin"},
+		Files:       []uintptr{0, out.Fd(), 2},
+		CloneFlags:  syscall.CLONE_NEWUSER,
+		DisableASLR: true,
+		TimeOffsets: &TimeOffsets{
+			Monotonic: -time.Second / 2,
+			Boottime:  boottime,
+		},
+	}
+	pid, err := r.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ws syscall.WaitStatus
+	if _, err := syscall.Wait4(pid, &ws, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !ws.Exited() || ws.ExitStatus() != 0 {
+		t.Fatalf("wait status = %#x", ws)
+	}
+	b, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	personality, uptime, _ := strings.Cut(string(b), "\n")
+	if p, err := strconv.ParseUint(personality, 16, 32); err != nil || p&_ADDR_NO_RANDOMIZE == 0 {
+		t.Errorf("personality = %s, expected ADDR_NO_RANDOMIZE", personality)
+	}
+	up, _, _ := strings.Cut(uptime, " ")
+	if u, err := strconv.ParseFloat(up, 64); err != nil || u < boottime.Seconds() {
+		t.Errorf("uptime = %s, expected >= %v", up, boottime.Seconds())
+	}
+}
+
+func TestTimeOffsets_Content(t *testing.T) {
+	tests := []struct {
+		offsets *TimeOffsets
+		content string
+	}{
+		{nil, ""},
+		{&TimeOffsets{}, ""},
+		{&TimeOffsets{Monotonic: 1500 * time.Millisecond}, "monotonic 1 500000000\n"},
+		{&TimeOffsets{Monotonic: -1500 * time.Millisecond, Boottime: time.Hour}, "monotonic -2 500000000\nboottime 3600 0\n"},
+	}
+	for _, tc := range tests {
+		if got := string(tc.offsets.content()); got != tc.content {
+			t.Errorf("%v: content = %q, expected %q", tc.offsets, got, tc.content)
+		}
+	}
+}
+
+func TestFork_MountAttr(t *testing.T) {
+	t.Parallel()
+	data := t.TempDir()
+	if err := os.WriteFile(filepath.Join(data, "file"), []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	m, err := mount.NewDefaultBuilder().
+		WithBindAttr(data, "d", unix.MOUNT_ATTR_RDONLY|unix.MOUNT_ATTR_NOEXEC, true).
+		FilterNotExist().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Runner{
+		Args:       []string{"/bin/sh", "-c", "test -r /d/file && ! echo > /d/new && ! /d/file"},
+		Env:        []string{"PATH=/usr/bin:/bin"},
+		Files:      []uintptr{0, 1, devNull(t)},
+		CloneFlags: syscall.CLONE_NEWNS | syscall.CLONE_NEWUSER,
+		Mounts:     m,
+		PivotRoot:  t.TempDir(),
+	}
+	pid, err := r.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitExitStatus(t, pid, 0)
+}
+
+func TestFork_IDMappedMount(t *testing.T) {
+	t.Parallel()
+	if os.Geteuid() != 0 {
+		t.Skip("root required for idmapped mount")
+	}
+	const mappedUID = 12345
+	ns, err := mount.NewUserNamespace(
+		[]syscall.SysProcIDMap{{ContainerID: 0, HostID: mappedUID, Size: 1}},
+		[]syscall.SysProcIDMap{{ContainerID: 0, HostID: mappedUID, Size: 1}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ns.Close()
+
+	data := t.TempDir()
+	if err := os.WriteFile(filepath.Join(data, "file"), []byte("test"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := mount.NewDefaultBuilder().
+		WithIDMappedBind(data, "d", "/proc/self/fd/"+strconv.Itoa(int(ns.Fd())), false).
+		FilterNotExist().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Runner{
+		Args:       []string{"/bin/sh", "-c", "test \"$(stat -c %u /d/file)\" = " + strconv.Itoa(mappedUID)},
+		Env:        []string{"PATH=/usr/bin:/bin"},
+		CloneFlags: syscall.CLONE_NEWNS,
+		Mounts:     m,
+		PivotRoot:  t.TempDir(),
+	}
+	pid, err := r.Start()
+	if e, ok := err.(ChildError); ok && e.Location == LocMountSetAttr && (e.Err == syscall.EINVAL || e.Err == syscall.ENOTSUP) {
+		t.Skip("idmapped mount is not supported:", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitExitStatus(t, pid, 0)
+}
+
+func devNull(t *testing.T) uintptr {
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { null.Close() })
+	return null.Fd()
+}
+
+func waitExitStatus(t *testing.T, pid int, status int) {
+	t.Helper()
+	var ws syscall.WaitStatus
+	if _, err := syscall.Wait4(pid, &ws, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !ws.Exited() || ws.ExitStatus() != status {
+		t.Errorf("wait status = %#x, expected exit status %d", ws, status)
+	}
+}
+
+func TestFork_ProcOptions(t *testing.T) {
+	t.Parallel()
+	m, err := mount.NewDefaultBuilder().
+		WithProcOptions(mount.ProcOptions{HidePid: mount.HidePidInvisible, SubsetPid: true}).
+		FilterNotExist().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Runner{
+		Args:       []string{"/bin/sh", "-c", "test -e /proc/self/exe && ! test -e /proc/meminfo"},
+		Env:        []string{"PATH=/usr/bin:/bin"},
+		CloneFlags: syscall.CLONE_NEWNS | syscall.CLONE_NEWUSER | syscall.CLONE_NEWPID,
+		Mounts:     m,
+		PivotRoot:  t.TempDir(),
+	}
+	pid, err := r.Start()
+	if e, ok := err.(ChildError); ok && e.Location == LocMount && e.Err == syscall.EINVAL {
+		t.Skip("proc mount options are not supported:", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitExitStatus(t, pid, 0)
+}
+
+// TestFork_ProcHidePidNumeric checks the numeric hidepid value supported
+// before kernel 5.8
+func TestFork_ProcHidePidNumeric(t *testing.T) {
+	t.Parallel()
+	m, err := mount.NewDefaultBuilder().
+		WithProcOptions(mount.ProcOptions{HidePid: "2"}).
+		FilterNotExist().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Runner{
+		Args:       []string{"/bin/sh", "-c", "test -e /proc/self/exe"},
+		Env:        []string{"PATH=/usr/bin:/bin"},
+		CloneFlags: syscall.CLONE_NEWNS | syscall.CLONE_NEWUSER | syscall.CLONE_NEWPID,
+		Mounts:     m,
+		PivotRoot:  t.TempDir(),
+	}
+	pid, err := r.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitExitStatus(t, pid, 0)
+}
+
+func TestFork_Devices(t *testing.T) {
+	t.Parallel()
+	m, err := mount.NewDefaultBuilder().
+		WithDevices().
+		WithDevPts().
+		FilterNotExist().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Runner{
+		Args: []string{"/bin/sh", "-c", "head -c 8 /dev/zero > /dev/null && head -c 8 /dev/urandom > /dev/null && " +
+			"! echo > /dev/full && test -c /dev/ptmx && test -c /dev/pts/ptmx"},
+		Env:        []string{"PATH=/usr/bin:/bin"},
+		Files:      []uintptr{0, 1, devNull(t)},
+		CloneFlags: syscall.CLONE_NEWNS | syscall.CLONE_NEWUSER,
+		Mounts:     m,
+		PivotRoot:  t.TempDir(),
+	}
+	pid, err := r.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitExitStatus(t, pid, 0)
+}
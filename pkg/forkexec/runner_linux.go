@@ -3,6 +3,7 @@ package forkexec
 import (
 	"syscall"
 
+	"github.com/tobiichi3227/go-sandbox/pkg/landlock"
 	"github.com/tobiichi3227/go-sandbox/pkg/mount"
 	"github.com/tobiichi3227/go-sandbox/pkg/rlimit"
 )
@@ -21,6 +22,19 @@ type Runner struct {
 	// POSIX Resource limit set by set rlimit
 	RLimits []rlimit.RLimit
 
+	// Sched defines CPU affinity, scheduling policy and I/O priority
+	Sched *Sched
+
+	// TimeOffsets unshares time namespace (kernel >= 5.6) and sets the clock
+	// offsets, the program enters the namespace after execve (kernel >= 5.11,
+	// otherwise only its children enter the namespace).
+	// need CAP_SYS_ADMIN & CAP_SYS_TIME (e.g. unshare user namespace)
+	TimeOffsets *TimeOffsets
+
+	// ProcFd is the directory fd of a proc file system used to write the time
+	// namespace offsets when /proc is not available (e.g. after pivot_root)
+	ProcFd uintptr
+
 	// file descriptors map for new process, from 0 to len - 1
 	Files []uintptr
 
@@ -31,6 +45,15 @@ type Runner struct {
 	// seccomp syscall filter applied to child
 	Seccomp *syscall.SockFprog
 
+	// landlock ruleset applied to child before seccomp (kernel >= 5.13)
+	// the ruleset is created before clone, thus paths are resolved in the
+	// current mount namespace. It is skipped if landlock is not supported
+	Landlock *landlock.Ruleset
+
+	// LandlockFd is the landlock ruleset fd created by the caller, it is used
+	// if Landlock is not defined (e.g. ruleset sent into the container)
+	LandlockFd uintptr
+
 	// clone unshare flag to create linux namespace, effective when clone child
 	// since unshare syscall does not join the new pid group
 	CloneFlags uintptr
@@ -63,9 +86,17 @@ type Runner struct {
 	UIDMappings []syscall.SysProcIDMap
 	GIDMappings []syscall.SysProcIDMap
 
+	// IDMapper, if non-nil, writes the uid / gid mappings instead of the parent
+	// (e.g. newuidmap / newgidmap for sub id ranges on non-root hosts)
+	IDMapper IDMapper
+
 	// CgroupFd to use when clone3 with CLONE_INTO_CGROUP with kernel >=5.7 and cgroup v2
 	CgroupFd uintptr
 
+	// PidFd, if non-nil, uses clone3 with CLONE_PIDFD (kernel >= 5.3) and stores
+	// the pidfd of the child, or -1 if not supported. Caller need to close it
+	PidFd *int
+
 	// Credential holds user and group identities to be assumed
 	// by a child process started by StartProcess.
 	Credential *syscall.Credential
@@ -81,7 +112,8 @@ type Runner struct {
 	Ptrace bool
 
 	// no_new_privs calls prctl(PR_SET_NO_NEW_PRIVS) to 0 to disable calls to
-	// setuid processes. It is automatically enabled when seccomp filter is provided
+	// setuid processes. It is automatically enabled when seccomp filter or
+	// landlock ruleset is provided
 	NoNewPrivs bool
 
 	// stop before seccomp calls kill(getpid(), SIGSTOP) to wait for tracer to continue
@@ -106,4 +138,8 @@ type Runner struct {
 
 	// CTTY specifies if set the fd 0 as controlling TTY
 	CTTY bool
+
+	// DisableASLR calls personality(ADDR_NO_RANDOMIZE) to disable address
+	// space randomization for reproducible execution
+	DisableASLR bool
 }
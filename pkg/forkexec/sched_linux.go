@@ -0,0 +1,52 @@
+package forkexec
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// I/O priority classes for ioprio_set
+const (
+	IOPrioClassRT   = 1
+	IOPrioClassBE   = 2
+	IOPrioClassIdle = 3
+)
+
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+)
+
+// Sched defines the CPU affinity, scheduling policy and I/O priority applied
+// to the child before execve
+type Sched struct {
+	// CPUs is the list of CPUs the child allowed to run on (sched_setaffinity)
+	// empty to inherit
+	CPUs []int
+
+	// Policy (SCHED_OTHER, SCHED_BATCH or SCHED_IDLE) set by sched_setscheduler
+	// and Nice set by setpriority. Both are inherited if both are zero
+	Policy int
+	Nice   int
+
+	// IOClass and IOLevel set by ioprio_set. Inherited if IOClass is zero
+	IOClass int
+	IOLevel int
+}
+
+// cpuSet prepares the affinity mask, nil if not set
+func (s *Sched) cpuSet() (*unix.CPUSet, error) {
+	if s == nil || len(s.CPUs) == 0 {
+		return nil, nil
+	}
+	set := new(unix.CPUSet)
+	for _, c := range s.CPUs {
+		if c < 0 || c >= int(unsafe.Sizeof(*set))*8 {
+			return nil, fmt.Errorf("forkexec: invalid cpu %d", c)
+		}
+		set.Set(c)
+	}
+	return set, nil
+}
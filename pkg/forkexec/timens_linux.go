@@ -0,0 +1,38 @@
+package forkexec
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeOffsets defines the clock offsets of the unshared time namespace
+type TimeOffsets struct {
+	Monotonic time.Duration
+	Boottime  time.Duration
+}
+
+// content formats the offsets to be written into /proc/self/timens_offsets
+func (t *TimeOffsets) content() []byte {
+	if t == nil {
+		return nil
+	}
+	var b []byte
+	for _, o := range []struct {
+		clock  string
+		offset time.Duration
+	}{
+		{"monotonic", t.Monotonic},
+		{"boottime", t.Boottime},
+	} {
+		if o.offset == 0 {
+			continue
+		}
+		// nanoseconds part must be in [0, 1e9)
+		sec, nsec := int64(o.offset/time.Second), int64(o.offset%time.Second)
+		if nsec < 0 {
+			sec, nsec = sec-1, nsec+int64(time.Second)
+		}
+		b = fmt.Appendf(b, "%s %d %d\n", o.clock, sec, nsec)
+	}
+	return b
+}
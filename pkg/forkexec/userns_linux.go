@@ -7,6 +7,20 @@ import (
 	"golang.org/x/sys/unix"
 )
 
+// IDMapper writes the uid / gid mappings for the child process in the new user
+// namespace, nil mappings map the root in the namespace to the current euid / egid
+type IDMapper interface {
+	MapIDs(pid int, uidMappings, gidMappings []syscall.SysProcIDMap) error
+}
+
+// mapIDs writes the id mappings through IDMapper if provided
+func (r *Runner) mapIDs(pid int) error {
+	if r.IDMapper != nil {
+		return r.IDMapper.MapIDs(pid, r.UIDMappings, r.GIDMappings)
+	}
+	return writeIDMaps(r, pid)
+}
+
 // writeUidGidMappings writes User ID and Group ID mappings for user namespaces
 // for a process and it is called from the parent process.
 func writeIDMaps(r *Runner, pid int) error {
@@ -0,0 +1,10 @@
+// Package idmap provides the uid / gid mapping of user namespaces with the
+// subordinate id ranges (/etc/subuid, /etc/subgid) for non-root hosts.
+//
+// Unprivileged process is only allowed to map its own uid / gid into a new
+// user namespace, the setuid helpers newuidmap / newgidmap (shadow-utils)
+// write multi-id mappings within the ranges granted to the user.
+//
+// Helper implements forkexec.IDMapper through the helpers and CredGenerator
+// hands out distinct credentials from the ranges for container.CredGenerator.
+package idmap
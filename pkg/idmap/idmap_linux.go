@@ -0,0 +1,196 @@
+package idmap
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// Default sub id files and helpers
+const (
+	SubUIDFile = "/etc/subuid"
+	SubGIDFile = "/etc/subgid"
+
+	NewUIDMap = "newuidmap"
+	NewGIDMap = "newgidmap"
+)
+
+// Range defines a range of subordinate ids [Start, Start + Count)
+type Range struct {
+	Start int
+	Count int
+}
+
+// ParseSubID parses the sub id file content (name:start:count) and returns
+// ranges granted to the user name or the numeric id
+func ParseSubID(r io.Reader, name string, id int) ([]Range, error) {
+	var ret []Range
+	idStr := strconv.Itoa(id)
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.Split(line, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("idmap: invalid line: %q", line)
+		}
+		if parts[0] != name && parts[0] != idStr {
+			continue
+		}
+		start, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("idmap: invalid start: %q: %w", line, err)
+		}
+		count, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("idmap: invalid count: %q: %w", line, err)
+		}
+		if start < 0 || count <= 0 {
+			return nil, fmt.Errorf("idmap: invalid range: %q", line)
+		}
+		ret = append(ret, Range{Start: start, Count: count})
+	}
+	return ret, s.Err()
+}
+
+// LookupSubUID returns the sub uid ranges of the current user
+func LookupSubUID() ([]Range, error) {
+	return lookupSubID(SubUIDFile)
+}
+
+// LookupSubGID returns the sub gid ranges of the current user. Both files are
+// keyed by the user name or uid, the gid is not used
+func LookupSubGID() ([]Range, error) {
+	return lookupSubID(SubGIDFile)
+}
+
+func lookupSubID(path string) ([]Range, error) {
+	uid := os.Getuid()
+	var name string
+	if u, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+		name = u.Username
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("idmap: %w", err)
+	}
+	defer f.Close()
+	return ParseSubID(f, name, uid)
+}
+
+// Helper writes id mappings through the newuidmap / newgidmap setuid helpers,
+// empty path looks up the default helper in PATH
+type Helper struct {
+	NewUIDMap string
+	NewGIDMap string
+}
+
+// MapIDs implements forkexec.IDMapper
+func (h *Helper) MapIDs(pid int, uidMappings, gidMappings []syscall.SysProcIDMap) error {
+	if uidMappings == nil {
+		uidMappings = []syscall.SysProcIDMap{{HostID: os.Geteuid(), Size: 1}}
+	}
+	if gidMappings == nil {
+		gidMappings = []syscall.SysProcIDMap{{HostID: os.Getegid(), Size: 1}}
+	}
+	if err := runHelper(orDefault(h.NewUIDMap, NewUIDMap), pid, uidMappings); err != nil {
+		return err
+	}
+	return runHelper(orDefault(h.NewGIDMap, NewGIDMap), pid, gidMappings)
+}
+
+func runHelper(path string, pid int, m []syscall.SysProcIDMap) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command(path, helperArgs(pid, m)...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("idmap: %s: %w: %s", path, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return nil
+}
+
+// helperArgs formats arguments as "pid id host-id count [id host-id count]..."
+func helperArgs(pid int, m []syscall.SysProcIDMap) []string {
+	args := make([]string, 0, 1+3*len(m))
+	args = append(args, strconv.Itoa(pid))
+	for _, im := range m {
+		args = append(args, strconv.Itoa(im.ContainerID), strconv.Itoa(im.HostID), strconv.Itoa(im.Size))
+	}
+	return args
+}
+
+func orDefault(s, d string) string {
+	if s == "" {
+		return d
+	}
+	return s
+}
+
+// CredGenerator hands out distinct uid / gid from the sub id ranges in turn,
+// and wraps around when the ranges are exhausted
+type CredGenerator struct {
+	UIDs []Range
+	GIDs []Range
+
+	mu   sync.Mutex
+	next int
+}
+
+// NewCredGenerator creates credential generator from the sub id ranges of
+// the current user
+func NewCredGenerator() (*CredGenerator, error) {
+	uids, err := LookupSubUID()
+	if err != nil {
+		return nil, err
+	}
+	gids, err := LookupSubGID()
+	if err != nil {
+		return nil, err
+	}
+	if len(uids) == 0 || len(gids) == 0 {
+		return nil, fmt.Errorf("idmap: no sub id range for the current user")
+	}
+	return &CredGenerator{UIDs: uids, GIDs: gids}, nil
+}
+
+// Get returns the next credential, it implements container.CredGenerator
+func (g *CredGenerator) Get() syscall.Credential {
+	g.mu.Lock()
+	n := g.next
+	g.next++
+	g.mu.Unlock()
+
+	return syscall.Credential{
+		Uid: uint32(nth(g.UIDs, n)),
+		Gid: uint32(nth(g.GIDs, n)),
+	}
+}
+
+// nth returns the n-th id (modulo total count) in ranges
+func nth(r []Range, n int) int {
+	total := 0
+	for _, rg := range r {
+		total += rg.Count
+	}
+	if total == 0 {
+		return 0
+	}
+	n %= total
+	for _, rg := range r {
+		if n < rg.Count {
+			return rg.Start + n
+		}
+		n -= rg.Count
+	}
+	return 0
+}
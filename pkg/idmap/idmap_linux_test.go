@@ -0,0 +1,201 @@
+package idmap
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/tobiichi3227/go-sandbox/pkg/forkexec"
+)
+
+// subIDEnv makes the test binary print the ranges looked up from the file
+const subIDEnv = "IDMAP_TEST_SUBID"
+
+func TestMain(m *testing.M) {
+	if p := os.Getenv(subIDEnv); p != "" {
+		r, err := lookupSubID(p)
+		if err != nil {
+			os.Exit(2)
+		}
+		fmt.Print(r)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestParseSubID(t *testing.T) {
+	const content = `
+# comment
+alice:100000:65536
+1000:200000:1000
+bob:300000:65536
+alice:400000:10
+`
+	r, err := ParseSubID(strings.NewReader(content), "alice", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Range{{100000, 65536}, {200000, 1000}, {400000, 10}}
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("ranges = %v, expected %v", r, expected)
+	}
+
+	for _, c := range []string{"alice:1", "alice:a:1", "alice:1:0"} {
+		if _, err := ParseSubID(strings.NewReader(c), "alice", 1000); err == nil {
+			t.Errorf("%q: expected error", c)
+		}
+	}
+}
+
+// TestLookupSubGID checks that sub gid is looked up by uid rather than gid
+func TestLookupSubGID(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("need root to run with a different gid")
+	}
+	uid, gid := os.Getuid(), os.Getuid()+4242
+	p := filepath.Join(t.TempDir(), "subgid")
+	content := fmt.Sprintf("%d:1:1\n%d:100000:65536\n", gid, uid)
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("/proc/self/exe", "-test.run=^$")
+	cmd.Env = []string{subIDEnv + "=" + p}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Credential: &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid)},
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := fmt.Sprint([]Range{{100000, 65536}}); string(out) != expected {
+		t.Errorf("ranges = %s, expected %s", out, expected)
+	}
+}
+
+func TestCredGenerator(t *testing.T) {
+	g := &CredGenerator{
+		UIDs: []Range{{100, 2}, {200, 1}},
+		GIDs: []Range{{500, 10}},
+	}
+	expected := []syscall.Credential{
+		{Uid: 100, Gid: 500},
+		{Uid: 101, Gid: 501},
+		{Uid: 200, Gid: 502},
+		{Uid: 100, Gid: 503},
+	}
+	for i, e := range expected {
+		if c := g.Get(); c.Uid != e.Uid || c.Gid != e.Gid {
+			t.Errorf("%d: cred = %d:%d, expected %d:%d", i, c.Uid, c.Gid, e.Uid, e.Gid)
+		}
+	}
+}
+
+func TestHelperArgs(t *testing.T) {
+	args := helperArgs(42, []syscall.SysProcIDMap{
+		{ContainerID: 0, HostID: 1000, Size: 1},
+		{ContainerID: 1, HostID: 100000, Size: 65536},
+	})
+	expected := []string{"42", "0", "1000", "1", "1", "100000", "65536"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("args = %v, expected %v", args, expected)
+	}
+}
+
+func TestHelper_MapIDs(t *testing.T) {
+	if _, err := exec.LookPath(NewUIDMap); err != nil {
+		t.Skip("newuidmap is not available")
+	}
+	uids, err := LookupSubUID()
+	if err != nil || len(uids) == 0 {
+		t.Skip("no sub uid range for the current user")
+	}
+	gids, err := LookupSubGID()
+	if err != nil || len(gids) == 0 {
+		t.Skip("no sub gid range for the current user")
+	}
+	r := forkexec.Runner{
+		Args:       []string{"/bin/true"},
+		CloneFlags: syscall.CLONE_NEWUSER,
+		UIDMappings: []syscall.SysProcIDMap{
+			{ContainerID: 0, HostID: syscall.Geteuid(), Size: 1},
+			{ContainerID: 1, HostID: uids[0].Start, Size: 1},
+		},
+		GIDMappings: []syscall.SysProcIDMap{
+			{ContainerID: 0, HostID: syscall.Getegid(), Size: 1},
+			{ContainerID: 1, HostID: gids[0].Start, Size: 1},
+		},
+		IDMapper: &Helper{},
+	}
+	pid, err := r.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ws syscall.WaitStatus
+	syscall.Wait4(pid, &ws, 0, nil)
+	if !ws.Exited() || ws.ExitStatus() != 0 {
+		t.Errorf("wait status = %#x", ws)
+	}
+}
+
+func TestHelper_Script(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("need root to write arbitrary id mappings")
+	}
+	// mock helper writes mappings directly
+	dir := t.TempDir()
+	for _, n := range []string{"uid", "gid"} {
+		script := "#!/bin/sh\npid=$1; shift\nprintf '%s %s %s\\n' \"$@\" > /proc/$pid/" + n + "_map\n"
+		if err := os.WriteFile(filepath.Join(dir, n), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err := os.CreateTemp(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	r := forkexec.Runner{
+		Args:       []string{"/bin/cat", "/proc/self/uid_map"},
+		Files:      []uintptr{0, out.Fd(), 2},
+		CloneFlags: syscall.CLONE_NEWUSER,
+		UIDMappings: []syscall.SysProcIDMap{
+			{ContainerID: 0, HostID: 0, Size: 1},
+			{ContainerID: 1000, HostID: 100000, Size: 10},
+		},
+		IDMapper: &Helper{NewUIDMap: filepath.Join(dir, "uid"), NewGIDMap: filepath.Join(dir, "gid")},
+	}
+	pid, err := r.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ws syscall.WaitStatus
+	syscall.Wait4(pid, &ws, 0, nil)
+	if !ws.Exited() || ws.ExitStatus() != 0 {
+		t.Fatalf("wait status = %#x", ws)
+	}
+	b, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := strings.Fields(string(b)); !reflect.DeepEqual(f, []string{"0", "0", "1", "1000", "100000", "10"}) {
+		t.Errorf("uid_map = %q", b)
+	}
+}
+
+func TestHelper_Error(t *testing.T) {
+	r := forkexec.Runner{
+		Args:       []string{"/bin/true"},
+		CloneFlags: syscall.CLONE_NEWUSER,
+		IDMapper:   &Helper{NewUIDMap: "/nonexistent/newuidmap"},
+	}
+	if _, err := r.Start(); err == nil || !strings.Contains(err.Error(), "newuidmap") {
+		t.Errorf("expected helper error, got %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+// Package landlock provides the definition of landlock ruleset to restrict the
+// filesystem and network access of a process (kernel >= 5.13).
+//
+// The ruleset is created in the parent process with the access rights
+// supported by the running kernel (graceful downgrade for older ABI), and the
+// child process calls landlock_restrict_self with the ruleset fd.
+//
+// ABI versions:
+//
+//	1 (5.13): filesystem access rights
+//	2 (5.19): refer (link / rename across directories)
+//	3 (6.2): truncate
+//	4 (6.7): TCP bind / connect
+//	5 (6.10): ioctl on device files
+package landlock
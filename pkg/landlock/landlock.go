@@ -0,0 +1,95 @@
+package landlock
+
+// Access defines the filesystem access rights of a path hierarchy
+type Access uint64
+
+// Filesystem access rights
+const (
+	AccessExecute Access = 1 << iota
+	AccessWriteFile
+	AccessReadFile
+	AccessReadDir
+	AccessRemoveDir
+	AccessRemoveFile
+	AccessMakeChar
+	AccessMakeDir
+	AccessMakeReg
+	AccessMakeSock
+	AccessMakeFifo
+	AccessMakeBlock
+	AccessMakeSym
+	AccessRefer    // ABI 2
+	AccessTruncate // ABI 3
+	AccessIoctlDev // ABI 5
+)
+
+// Access right groups for path rules
+const (
+	// AccessRead allows read files and list directories
+	AccessRead = AccessReadFile | AccessReadDir
+	// AccessWrite allows write, create and remove files and directories
+	AccessWrite = AccessWriteFile | AccessRemoveDir | AccessRemoveFile | AccessMakeChar |
+		AccessMakeDir | AccessMakeReg | AccessMakeSock | AccessMakeFifo | AccessMakeBlock |
+		AccessMakeSym | AccessRefer | AccessTruncate
+	// AccessReadExec allows read and execute files
+	AccessReadExec = AccessRead | AccessExecute
+	// AccessAll allows all access
+	AccessAll = AccessReadExec | AccessWrite | AccessIoctlDev
+
+	// accessFile is the access rights applicable to regular files
+	accessFile = AccessExecute | AccessWriteFile | AccessReadFile | AccessTruncate | AccessIoctlDev
+)
+
+// NetAccess defines the network access rights of a TCP port
+type NetAccess uint64
+
+// Network access rights (ABI 4)
+const (
+	NetBindTCP NetAccess = 1 << iota
+	NetConnectTCP
+)
+
+// PathRule allows the access rights beneath the path (file or directory)
+type PathRule struct {
+	Path   string
+	Access Access
+}
+
+// NetRule allows the access rights to the TCP port
+type NetRule struct {
+	Port   uint16
+	Access NetAccess
+}
+
+// Ruleset defines the landlock restrictions. All filesystem access supported
+// by the kernel is denied unless allowed by path rules. Paths are opened in
+// the mount namespace of the calling process
+type Ruleset struct {
+	Paths []PathRule
+
+	// RestrictNet denies TCP bind / connect unless allowed by net rules, it is
+	// ignored if the kernel ABI < 4
+	RestrictNet bool
+	Net         []NetRule
+
+	// MinABI fails the ruleset creation if the kernel ABI is lower, otherwise
+	// the ruleset is downgraded and not applied if landlock is not supported
+	MinABI int
+}
+
+// WithPath appends path rules with the same access rights
+func (r *Ruleset) WithPath(access Access, paths ...string) *Ruleset {
+	for _, p := range paths {
+		r.Paths = append(r.Paths, PathRule{Path: p, Access: access})
+	}
+	return r
+}
+
+// WithNet appends net rules with the same access rights and enables RestrictNet
+func (r *Ruleset) WithNet(access NetAccess, ports ...uint16) *Ruleset {
+	r.RestrictNet = true
+	for _, p := range ports {
+		r.Net = append(r.Net, NetRule{Port: p, Access: access})
+	}
+	return r
+}
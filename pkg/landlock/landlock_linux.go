@@ -0,0 +1,119 @@
+package landlock
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// landlock rule type for TCP port (not defined in unix package)
+const ruleNetPort = 2
+
+// netPortAttr is struct landlock_net_port_attr
+type netPortAttr struct {
+	allowedAccess uint64
+	port          uint64
+}
+
+// accessByABI is the filesystem access rights supported by each ABI version
+var accessByABI = []Access{
+	0,
+	AccessMakeSym<<1 - 1,
+	AccessRefer<<1 - 1,
+	AccessTruncate<<1 - 1,
+	AccessTruncate<<1 - 1,
+	AccessIoctlDev<<1 - 1,
+}
+
+// ABI returns the landlock ABI version of the kernel, 0 if not supported
+func ABI() int {
+	r, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return 0
+	}
+	return int(r)
+}
+
+// Create creates the ruleset fd with rules added. It returns -1 if landlock
+// is not supported by the kernel. Caller need to close the fd
+func (r *Ruleset) Create() (int, error) {
+	abi := ABI()
+	if abi < r.MinABI {
+		return -1, fmt.Errorf("landlock: ABI %d is lower than required %d", abi, r.MinABI)
+	}
+	if abi == 0 {
+		return -1, nil
+	}
+	handled := accessByABI[min(abi, len(accessByABI)-1)]
+	attr := unix.LandlockRulesetAttr{
+		Access_fs: uint64(handled),
+	}
+	restrictNet := r.RestrictNet && abi >= 4
+	if restrictNet {
+		attr.Access_net = uint64(NetBindTCP | NetConnectTCP)
+	}
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return -1, fmt.Errorf("landlock: create ruleset: %w", errno)
+	}
+	if err := r.addRules(int(fd), handled, restrictNet); err != nil {
+		unix.Close(int(fd))
+		return -1, err
+	}
+	return int(fd), nil
+}
+
+func (r *Ruleset) addRules(fd int, handled Access, restrictNet bool) error {
+	for _, p := range r.Paths {
+		if err := addPathRule(fd, p.Path, p.Access&handled); err != nil {
+			return fmt.Errorf("landlock: add rule %q: %w", p.Path, err)
+		}
+	}
+	if !restrictNet {
+		return nil
+	}
+	for _, n := range r.Net {
+		attr := netPortAttr{
+			allowedAccess: uint64(n.Access & (NetBindTCP | NetConnectTCP)),
+			port:          uint64(n.Port),
+		}
+		if attr.allowedAccess == 0 {
+			continue
+		}
+		_, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(fd), ruleNetPort, uintptr(unsafe.Pointer(&attr)), 0, 0, 0)
+		if errno != 0 {
+			return fmt.Errorf("landlock: add rule port %d: %w", n.Port, errno)
+		}
+	}
+	return nil
+}
+
+func addPathRule(fd int, path string, access Access) error {
+	pathFd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(pathFd)
+
+	// directory only access rights are invalid for files
+	var st unix.Stat_t
+	if err := unix.Fstat(pathFd, &st); err != nil {
+		return err
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFDIR {
+		access &= accessFile
+	}
+	if access == 0 {
+		return nil
+	}
+	attr := unix.LandlockPathBeneathAttr{
+		Allowed_access: uint64(access),
+		Parent_fd:      int32(pathFd),
+	}
+	_, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(fd), unix.LANDLOCK_RULE_PATH_BENEATH, uintptr(unsafe.Pointer(&attr)), 0, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
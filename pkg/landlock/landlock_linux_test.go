@@ -0,0 +1,49 @@
+package landlock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestRuleset_Create(t *testing.T) {
+	abi := ABI()
+	if abi == 0 {
+		t.Skip("landlock is not supported")
+	}
+	dir := t.TempDir()
+	f := filepath.Join(dir, "file")
+	if err := os.WriteFile(f, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		rs   *Ruleset
+		ok   bool
+	}{
+		{"empty", &Ruleset{}, true},
+		{"dir", (&Ruleset{}).WithPath(AccessAll, dir), true},
+		// directory only access rights are dropped for files
+		{"file", (&Ruleset{}).WithPath(AccessAll, f), true},
+		{"net", (&Ruleset{}).WithNet(NetConnectTCP, 80, 443), true},
+		{"not exist", (&Ruleset{}).WithPath(AccessRead, filepath.Join(dir, "none")), false},
+		{"min abi", &Ruleset{MinABI: abi + 1}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fd, err := tc.rs.Create()
+			if fd >= 0 {
+				unix.Close(fd)
+			}
+			if tc.ok != (err == nil) {
+				t.Fatalf("create error = %v, expected ok = %v", err, tc.ok)
+			}
+			if tc.ok && fd < 0 {
+				t.Fatalf("create returned invalid fd")
+			}
+		})
+	}
+}
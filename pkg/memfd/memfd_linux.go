@@ -36,14 +36,20 @@ func DupToMemfd(name string, reader io.Reader) (*os.File, error) {
 		file.Close()
 		return nil, fmt.Errorf("memfd: read from: %w", err)
 	}
-	// make memfd readonly
-	if _, err = unix.FcntlInt(file.Fd(), unix.F_ADD_SEALS, roSeal); err != nil {
+	if err = Seal(file); err != nil {
 		file.Close()
-		return nil, fmt.Errorf("memfd: seal: %w", err)
+		return nil, err
+	}
+	return file, nil
+}
+
+// Seal makes the memfd readonly and seeks to the start
+func Seal(file *os.File) error {
+	if _, err := unix.FcntlInt(file.Fd(), unix.F_ADD_SEALS, roSeal); err != nil {
+		return fmt.Errorf("memfd: seal: %w", err)
 	}
 	if _, err := file.Seek(0, 0); err != nil {
-		file.Close()
-		return nil, fmt.Errorf("memfd: seek: %w", err)
+		return fmt.Errorf("memfd: seek: %w", err)
 	}
-	return file, nil
+	return nil
 }
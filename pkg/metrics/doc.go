@@ -0,0 +1,4 @@
+// Package metrics records the sandbox execution metrics including runner
+// results, container command latencies and cgroup operation errors, and
+// exposes them in Prometheus text format through http.Handler.
+package metrics
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/tobiichi3227/go-sandbox/container"
+	"github.com/tobiichi3227/go-sandbox/runner"
+)
+
+var _ container.Environment = &Environment{}
+
+// Environment wraps a container.Environment to record the latency and error
+// of its commands
+type Environment struct {
+	container.Environment
+	Metrics *Metrics
+}
+
+func (e *Environment) observe(cmd string, start time.Time, err error) {
+	e.Metrics.ObserveContainerCommand(cmd, time.Since(start), err)
+}
+
+// Ping implements container.Environment
+func (e *Environment) Ping() error {
+	start := time.Now()
+	err := e.Environment.Ping()
+	e.observe("ping", start, err)
+	return err
+}
+
+// Open implements container.Environment
+func (e *Environment) Open(p []container.OpenCmd) ([]*os.File, error) {
+	start := time.Now()
+	f, err := e.Environment.Open(p)
+	e.observe("open", start, err)
+	return f, err
+}
+
+// Delete implements container.Environment
+func (e *Environment) Delete(p string) error {
+	start := time.Now()
+	err := e.Environment.Delete(p)
+	e.observe("delete", start, err)
+	return err
+}
+
+// Reset implements container.Environment
+func (e *Environment) Reset() error {
+	start := time.Now()
+	err := e.Environment.Reset()
+	e.observe("reset", start, err)
+	return err
+}
+
+// Execve implements container.Environment, runner error is recorded as
+// command error
+func (e *Environment) Execve(c context.Context, param container.ExecveParam) runner.Result {
+	start := time.Now()
+	result := e.Environment.Execve(c, param)
+	var err error
+	if result.Status == runner.StatusRunnerError {
+		err = errors.New(result.Error)
+	}
+	e.observe("execve", start, err)
+	return result
+}
+
+// Destroy implements container.Environment
+func (e *Environment) Destroy() error {
+	start := time.Now()
+	err := e.Environment.Destroy()
+	e.observe("destroy", start, err)
+	return err
+}
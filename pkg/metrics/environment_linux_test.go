@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tobiichi3227/go-sandbox/container"
+	"github.com/tobiichi3227/go-sandbox/runner"
+)
+
+type fakeEnvironment struct {
+	container.Environment
+}
+
+func (fakeEnvironment) Ping() error { return nil }
+
+func (fakeEnvironment) Reset() error { return errors.New("reset failed") }
+
+func (fakeEnvironment) Execve(context.Context, container.ExecveParam) runner.Result {
+	return runner.Result{Status: runner.StatusRunnerError, Error: "execve failed"}
+}
+
+func TestEnvironment(t *testing.T) {
+	m := New()
+	e := &Environment{Environment: fakeEnvironment{}, Metrics: m}
+	if err := e.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Reset(); err == nil {
+		t.Fatal("expected reset error")
+	}
+	if r := e.Execve(context.Background(), container.ExecveParam{}); r.Status != runner.StatusRunnerError {
+		t.Fatalf("unexpected result %v", r)
+	}
+
+	var sb strings.Builder
+	m.WriteTo(&sb)
+	out := sb.String()
+	for _, want := range []string{
+		"sandbox_container_command_duration_seconds_count{command=\"ping\"} 1\n",
+		"sandbox_container_command_errors_total{command=\"reset\"} 1\n",
+		"sandbox_container_command_errors_total{command=\"execve\"} 1\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "errors_total{command=\"ping\"}") {
+		t.Error("ping should not be recorded as error")
+	}
+}
@@ -0,0 +1,270 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tobiichi3227/go-sandbox/runner"
+)
+
+// DefaultBuckets are the default histogram buckets in seconds
+var DefaultBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// contentType is the Prometheus text exposition format
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Metrics records sandbox execution metrics, it is safe for concurrent use.
+// It implements runner.Observer to record results through runner.Observed
+// and http.Handler to be scraped
+type Metrics struct {
+	buckets []float64
+
+	mu          sync.Mutex
+	setUpTime   *histogram
+	runningTime *histogram
+	cpuTime     *histogram
+	results     map[string]uint64 // by status name
+	causes      map[string]uint64 // by runtime error name
+	limitHits   map[string]uint64 // by status name
+
+	commandTime   map[string]*histogram // by container command
+	commandErrors map[string]uint64
+	cgroupOps     map[string]uint64 // by cgroup operation
+	cgroupErrors  map[string]uint64
+	poolIdle      int
+	poolBusy      int
+}
+
+// New creates metrics with histogram buckets in seconds, DefaultBuckets if empty
+func New(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	return &Metrics{
+		buckets:       buckets,
+		setUpTime:     newHistogram(buckets),
+		runningTime:   newHistogram(buckets),
+		cpuTime:       newHistogram(buckets),
+		results:       make(map[string]uint64),
+		causes:        make(map[string]uint64),
+		limitHits:     make(map[string]uint64),
+		commandTime:   make(map[string]*histogram),
+		commandErrors: make(map[string]uint64),
+		cgroupOps:     make(map[string]uint64),
+		cgroupErrors:  make(map[string]uint64),
+	}
+}
+
+// ObserveResult records the result of an execution
+func (m *Metrics) ObserveResult(r runner.Result) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.setUpTime.observe(r.SetUpTime.Seconds())
+	m.runningTime.observe(r.RunningTime.Seconds())
+	m.cpuTime.observe(r.Time.Seconds())
+	m.results[r.Status.Name()]++
+	if r.RuntimeError != runner.RuntimeErrorNone {
+		m.causes[r.RuntimeError.Name()]++
+	}
+}
+
+// ObserveContainerCommand records the latency and error of a container
+// command (e.g. "execve")
+func (m *Metrics) ObserveContainerCommand(cmd string, d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.commandTime[cmd]
+	if !ok {
+		h = newHistogram(m.buckets)
+		m.commandTime[cmd] = h
+	}
+	h.observe(d.Seconds())
+	if err != nil {
+		m.commandErrors[cmd]++
+	}
+}
+
+// ObserveCgroup records a cgroup operation (e.g. "create") and its error,
+// err is returned as is
+func (m *Metrics) ObserveCgroup(op string, err error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.cgroupOps[op]++
+	if err != nil {
+		m.cgroupErrors[op]++
+	}
+	return err
+}
+
+// SetContainerPool sets the number of idle and busy containers in the pool
+func (m *Metrics) SetContainerPool(idle, busy int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.poolIdle, m.poolBusy = idle, busy
+}
+
+// OnStart implements runner.Observer
+func (m *Metrics) OnStart(int) {}
+
+// OnExec implements runner.Observer
+func (m *Metrics) OnExec() {}
+
+// OnLimitHit implements runner.Observer
+func (m *Metrics) OnLimitHit(status runner.Status) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.limitHits[status.Name()]++
+}
+
+// OnExit implements runner.Observer
+func (m *Metrics) OnExit(result runner.Result) {
+	m.ObserveResult(result)
+}
+
+// ServeHTTP writes the metrics in Prometheus text format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in Prometheus text format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	writeHistogram(cw, "sandbox_setup_duration_seconds", "Time to set up the program before execve.", "", map[string]*histogram{"": m.setUpTime})
+	writeHistogram(cw, "sandbox_running_duration_seconds", "Real time of the program running.", "", map[string]*histogram{"": m.runningTime})
+	writeHistogram(cw, "sandbox_cpu_time_seconds", "User CPU time used by the program.", "", map[string]*histogram{"": m.cpuTime})
+	writeCounter(cw, "sandbox_results_total", "Executions by result status.", "status", m.results)
+	writeCounter(cw, "sandbox_runtime_errors_total", "Executions by classified runtime error.", "cause", m.causes)
+	writeCounter(cw, "sandbox_limit_hits_total", "Limit hits detected by the runners.", "status", m.limitHits)
+	writeHistogram(cw, "sandbox_container_command_duration_seconds", "Latency of the container commands.", "command", m.commandTime)
+	writeCounter(cw, "sandbox_container_command_errors_total", "Failed container commands.", "command", m.commandErrors)
+	writeCounter(cw, "sandbox_cgroup_operations_total", "Cgroup operations.", "operation", m.cgroupOps)
+	writeCounter(cw, "sandbox_cgroup_errors_total", "Failed cgroup operations.", "operation", m.cgroupErrors)
+
+	cw.printf("# HELP sandbox_container_pool Containers in the pool by state.\n")
+	cw.printf("# TYPE sandbox_container_pool gauge\n")
+	cw.printf("sandbox_container_pool{state=\"busy\"} %d\n", m.poolBusy)
+	cw.printf("sandbox_container_pool{state=\"idle\"} %d\n", m.poolIdle)
+
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// histogram is a cumulative histogram protected by Metrics.mu
+type histogram struct {
+	buckets []float64
+	counts  []uint64 // counts[i] is the number of observations <= buckets[i]
+	count   uint64
+	sum     float64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// countWriter keeps the first error and the number of bytes written
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countWriter) printf(format string, a ...any) {
+	if c.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(c.w, format, a...)
+	c.n += int64(n)
+	c.err = err
+}
+
+func writeCounter(w *countWriter, name, help, label string, values map[string]uint64) {
+	w.printf("# HELP %s %s\n", name, help)
+	w.printf("# TYPE %s counter\n", name)
+	for _, k := range sortedKeys(values) {
+		w.printf("%s{%s=\"%s\"} %d\n", name, label, escapeLabel(k), values[k])
+	}
+}
+
+func writeHistogram(w *countWriter, name, help, label string, values map[string]*histogram) {
+	w.printf("# HELP %s %s\n", name, help)
+	w.printf("# TYPE %s histogram\n", name)
+	for _, k := range sortedKeys(values) {
+		h := values[k]
+		labels := ""
+		if label != "" {
+			labels = label + "=\"" + escapeLabel(k) + "\""
+		}
+		sep := ""
+		if labels != "" {
+			sep = ","
+		}
+		for i, b := range h.buckets {
+			w.printf("%s_bucket{%s%sle=\"%s\"} %d\n", name, labels, sep, formatFloat(b), h.counts[i])
+		}
+		w.printf("%s_bucket{%s%sle=\"+Inf\"} %d\n", name, labels, sep, h.count)
+		if labels != "" {
+			labels = "{" + labels + "}"
+		}
+		w.printf("%s_sum%s %s\n", name, labels, formatFloat(h.sum))
+		w.printf("%s_count%s %d\n", name, labels, h.count)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tobiichi3227/go-sandbox/runner"
+)
+
+func TestMetrics_WriteTo(t *testing.T) {
+	m := New(0.1, 1)
+	m.ObserveResult(runner.Result{
+		Status:      runner.StatusNormal,
+		Time:        50 * time.Millisecond,
+		SetUpTime:   20 * time.Millisecond,
+		RunningTime: 500 * time.Millisecond,
+	})
+	m.OnLimitHit(runner.StatusTimeLimitExceeded)
+	m.OnExit(runner.Result{
+		Status:       runner.StatusSignalled,
+		RuntimeError: runner.RuntimeErrorStackOverflow,
+		RunningTime:  2 * time.Second,
+	})
+	m.ObserveContainerCommand("execve", 200*time.Millisecond, nil)
+	m.ObserveContainerCommand("execve", time.Millisecond, errors.New("failed"))
+	if err := m.ObserveCgroup("create", errors.New("failed")); err == nil {
+		t.Error("ObserveCgroup should return the error")
+	}
+	m.ObserveCgroup("create", nil)
+	m.SetContainerPool(3, 1)
+
+	var sb strings.Builder
+	n, err := m.WriteTo(&sb)
+	if err != nil || n != int64(sb.Len()) {
+		t.Fatalf("WriteTo = %d, %v", n, err)
+	}
+	out := sb.String()
+	for _, want := range []string{
+		"# TYPE sandbox_setup_duration_seconds histogram\n",
+		"sandbox_running_duration_seconds_bucket{le=\"0.1\"} 0\n",
+		"sandbox_running_duration_seconds_bucket{le=\"1\"} 1\n",
+		"sandbox_running_duration_seconds_bucket{le=\"+Inf\"} 2\n",
+		"sandbox_running_duration_seconds_sum 2.5\n",
+		"sandbox_running_duration_seconds_count 2\n",
+		"sandbox_results_total{status=\"OK\"} 1\n",
+		"sandbox_results_total{status=\"SIG\"} 1\n",
+		"sandbox_runtime_errors_total{cause=\"STACK\"} 1\n",
+		"sandbox_limit_hits_total{status=\"TLE\"} 1\n",
+		"sandbox_container_command_duration_seconds_bucket{command=\"execve\",le=\"0.1\"} 1\n",
+		"sandbox_container_command_duration_seconds_count{command=\"execve\"} 2\n",
+		"sandbox_container_command_errors_total{command=\"execve\"} 1\n",
+		"sandbox_cgroup_operations_total{operation=\"create\"} 2\n",
+		"sandbox_cgroup_errors_total{operation=\"create\"} 1\n",
+		"sandbox_container_pool{state=\"idle\"} 3\n",
+		"sandbox_container_pool{state=\"busy\"} 1\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestMetrics_ServeHTTP(t *testing.T) {
+	m := New()
+	m.ObserveContainerCommand("a\"b\\c\n", time.Millisecond, nil)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	if want := `sandbox_container_command_errors_total`; !strings.Contains(string(body), want) {
+		t.Errorf("missing %q", want)
+	}
+	if want := `{command="a\"b\\c\n",le="0.001"} 1`; !strings.Contains(string(body), want) {
+		t.Errorf("missing escaped label %q in:\n%s", want, body)
+	}
+}
@@ -1,5 +1,7 @@
 package mount
 
+import "strings"
+
 // Builder builds fork_exec friendly mount syscall format
 type Builder struct {
 	Mounts []Mount
@@ -9,3 +11,41 @@ type Builder struct {
 func NewBuilder() *Builder {
 	return &Builder{}
 }
+
+// DefaultDevices defines the minimal device nodes for the sandbox
+var DefaultDevices = []string{"null", "zero", "full", "random", "urandom", "tty"}
+
+// HidePid values for proc file system (kernel >= 5.8)
+const (
+	HidePidOff        = "off"
+	HidePidNoAccess   = "noaccess"
+	HidePidInvisible  = "invisible"
+	HidePidPtraceable = "ptraceable"
+)
+
+// ProcOptions defines proc file system mount options
+type ProcOptions struct {
+	// ReadWrite mounts proc file system read-write
+	ReadWrite bool
+
+	// HidePid restricts access to processes of other users (hidepid=),
+	// e.g. HidePidInvisible hides the container init /proc/1. Mount fails
+	// with EINVAL for the names before kernel 5.8, use the numeric values
+	// (0: off, 1: noaccess, 2: invisible) to support older kernels
+	HidePid string
+
+	// SubsetPid hides all top level files and directories except process
+	// directories (subset=pid, kernel >= 5.8)
+	SubsetPid bool
+}
+
+func (o ProcOptions) data() string {
+	var opts []string
+	if o.HidePid != "" {
+		opts = append(opts, "hidepid="+o.HidePid)
+	}
+	if o.SubsetPid {
+		opts = append(opts, "subset=pid")
+	}
+	return strings.Join(opts, ",")
+}
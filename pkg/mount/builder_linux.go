@@ -92,6 +92,74 @@ func (b *Builder) WithBind(source, target string, readonly bool) *Builder {
 	return b
 }
 
+// WithBindAttr adds a bind mount with mount attributes (MOUNT_ATTR_*) set by
+// mount_setattr (kernel >= 5.12), e.g. MOUNT_ATTR_NOEXEC | MOUNT_ATTR_NODEV
+func (b *Builder) WithBindAttr(source, target string, attr uint64, recursive bool) *Builder {
+	var flags uintptr = bind
+	if attr&unix.MOUNT_ATTR_RDONLY != 0 {
+		flags |= unix.MS_RDONLY
+	}
+	b.Mounts = append(b.Mounts, Mount{
+		Source:    source,
+		Target:    target,
+		Flags:     flags,
+		Attr:      attr,
+		Recursive: recursive,
+	})
+	return b
+}
+
+// WithBindRecursiveReadOnly adds a bind mount that all sub mounts are also
+// read-only (kernel >= 5.12)
+func (b *Builder) WithBindRecursiveReadOnly(source, target string) *Builder {
+	return b.WithBindAttr(source, target, unix.MOUNT_ATTR_RDONLY|unix.MOUNT_ATTR_NOSUID, true)
+}
+
+// WithIDMappedBind adds a bind mount that file owners are mapped by the user
+// namespace at userNs (kernel >= 5.12, also need file system support)
+func (b *Builder) WithIDMappedBind(source, target, userNs string, readonly bool) *Builder {
+	var flags uintptr = bind
+	var attr uint64 = unix.MOUNT_ATTR_NOSUID
+	if readonly {
+		flags |= unix.MS_RDONLY
+		attr |= unix.MOUNT_ATTR_RDONLY
+	}
+	b.Mounts = append(b.Mounts, Mount{
+		Source:    source,
+		Target:    target,
+		Flags:     flags,
+		Attr:      attr,
+		Recursive: true,
+		UserNs:    userNs,
+	})
+	return b
+}
+
+// WithDevices adds bind mounts of host device nodes under dev since mknod is
+// not permitted inside user namespace, empty uses DefaultDevices
+func (b *Builder) WithDevices(names ...string) *Builder {
+	if len(names) == 0 {
+		names = DefaultDevices
+	}
+	for _, n := range names {
+		b.WithBind("/dev/"+n, "dev/"+n, false)
+	}
+	return b
+}
+
+// WithDevPts adds a new devpts instance at dev/pts and binds dev/ptmx, which
+// refers to the devpts next to it (kernel >= 4.7)
+func (b *Builder) WithDevPts() *Builder {
+	b.Mounts = append(b.Mounts, Mount{
+		Source: "devpts",
+		Target: "dev/pts",
+		FsType: "devpts",
+		Flags:  unix.MS_NOSUID | unix.MS_NOEXEC,
+		Data:   "newinstance,ptmxmode=0666,mode=0620",
+	})
+	return b.WithBind("/dev/ptmx", "dev/ptmx", false)
+}
+
 // WithTmpfs adds a tmpfs mount to builder
 func (b *Builder) WithTmpfs(target, data string) *Builder {
 	b.Mounts = append(b.Mounts, Mount{
@@ -111,8 +179,13 @@ func (b *Builder) WithProc() *Builder {
 
 // WithProcRW adds proc file system, possibly read-write
 func (b *Builder) WithProcRW(canWrite bool) *Builder {
+	return b.WithProcOptions(ProcOptions{ReadWrite: canWrite})
+}
+
+// WithProcOptions adds proc file system with mount options
+func (b *Builder) WithProcOptions(o ProcOptions) *Builder {
 	var flags uintptr = unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC
-	if !canWrite {
+	if !o.ReadWrite {
 		flags |= unix.MS_RDONLY
 	}
 	b.Mounts = append(b.Mounts, Mount{
@@ -120,6 +193,7 @@ func (b *Builder) WithProcRW(canWrite bool) *Builder {
 		Target: "proc",
 		FsType: "proc",
 		Flags:  flags,
+		Data:   o.data(),
 	})
 	return b
 }
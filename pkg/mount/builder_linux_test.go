@@ -4,6 +4,8 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"golang.org/x/sys/unix"
 )
 
 func TestBuilder_WithBind(t *testing.T) {
@@ -122,3 +124,65 @@ func TestBuilder_FilterNotExist(t *testing.T) {
 		t.Errorf("unexpected mount: %+v", b.Mounts[0])
 	}
 }
+
+func TestBuilder_WithBindRecursiveReadOnly(t *testing.T) {
+	b := NewBuilder().WithBindRecursiveReadOnly("/src", "/dst")
+	m := b.Mounts[0]
+	if !m.IsBindMount() || !m.IsReadOnly() {
+		t.Errorf("expected read-only bind mount: %+v", m)
+	}
+	if !m.Recursive || m.Attr&unix.MOUNT_ATTR_RDONLY == 0 {
+		t.Errorf("expected recursive read-only attr: %+v", m)
+	}
+}
+
+func TestBuilder_WithIDMappedBind(t *testing.T) {
+	b := NewBuilder().WithIDMappedBind("/src", "/dst", "/proc/self/ns/user", false)
+	m := b.Mounts[0]
+	if !m.IsBindMount() || m.IsReadOnly() || m.Attr&unix.MOUNT_ATTR_RDONLY != 0 {
+		t.Errorf("expected read-write bind mount: %+v", m)
+	}
+	if m.UserNs != "/proc/self/ns/user" {
+		t.Errorf("unexpected user namespace: %s", m.UserNs)
+	}
+	sp, err := m.ToSyscall()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sp.UserNs == nil || !sp.Recursive || sp.Attr != m.Attr {
+		t.Errorf("unexpected syscall params: %+v", sp)
+	}
+}
+
+func TestBuilder_WithProcOptions(t *testing.T) {
+	b := NewBuilder().WithProcOptions(ProcOptions{HidePid: HidePidInvisible, SubsetPid: true})
+	m := b.Mounts[0]
+	if m.FsType != "proc" || !m.IsReadOnly() {
+		t.Errorf("expected read-only proc mount: %+v", m)
+	}
+	if m.Data != "hidepid=invisible,subset=pid" {
+		t.Errorf("unexpected data: %s", m.Data)
+	}
+	if s := m.String(); s != "proc[ro,hidepid=invisible,subset=pid]" {
+		t.Errorf("unexpected string: %s", s)
+	}
+}
+
+func TestBuilder_WithDevices(t *testing.T) {
+	b := NewBuilder().WithDevices().WithDevPts()
+	if len(b.Mounts) != len(DefaultDevices)+2 {
+		t.Fatalf("expected %d mounts, got %d", len(DefaultDevices)+2, len(b.Mounts))
+	}
+	for i, n := range DefaultDevices {
+		m := b.Mounts[i]
+		if !m.IsBindMount() || m.IsReadOnly() || m.Source != "/dev/"+n || m.Target != "dev/"+n {
+			t.Errorf("unexpected device mount: %+v", m)
+		}
+	}
+	if m := b.Mounts[len(DefaultDevices)]; m.FsType != "devpts" || m.Target != "dev/pts" {
+		t.Errorf("unexpected devpts mount: %+v", m)
+	}
+	if m := b.Mounts[len(DefaultDevices)+1]; m.Source != "/dev/ptmx" || m.Target != "dev/ptmx" {
+		t.Errorf("unexpected ptmx mount: %+v", m)
+	}
+}
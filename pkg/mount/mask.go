@@ -0,0 +1,29 @@
+package mount
+
+// DefaultMaskPaths defines paths under /proc and /sys to be masked (by bind
+// mount /dev/null or read-only tmpfs) to avoid reading information from
+// outside of the sandbox, not existing paths are ignored
+var DefaultMaskPaths = []string{
+	// https://github.com/containerd/containerd/blob/f0a32c66dad1e9de716c9960af806105d691cd78/oci/spec.go#L165-L176
+	"/proc/acpi",
+	"/proc/asound",
+	"/proc/kcore",
+	"/proc/keys",
+	"/proc/latency_stats",
+	"/proc/timer_list",
+	"/proc/timer_stats",
+	"/proc/sched_debug",
+	"/sys/firmware",
+	"/proc/scsi",
+
+	// kernel symbols and logs, interrupts and power usage (side channels)
+	"/proc/kallsyms",
+	"/proc/kmsg",
+	"/proc/interrupts",
+	"/proc/sysrq-trigger",
+	"/proc/config.gz",
+	"/sys/kernel/debug",
+	"/sys/devices/virtual/powercap",
+
+	"/usr/lib/wsl",
+}
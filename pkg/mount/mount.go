@@ -8,6 +8,16 @@ import (
 type Mount struct {
 	Source, Target, FsType, Data string
 	Flags                        uintptr
+
+	// Attr sets mount attributes (MOUNT_ATTR_*) by mount_setattr after mount
+	// (kernel >= 5.12), Recursive applies them to all sub mounts (AT_RECURSIVE)
+	Attr      uint64
+	Recursive bool
+
+	// UserNs is the path of the user namespace for idmapped bind mount (e.g.
+	// /proc/[pid]/ns/user), the source is cloned by open_tree and attached by
+	// move_mount with MOUNT_ATTR_IDMAP, Flags other than MS_REC are ignored
+	UserNs string
 }
 
 // SyscallParams defines the raw syscall arguments to mount
@@ -17,6 +27,9 @@ type SyscallParams struct {
 	Prefixes                     []*byte
 	MakeNod                      bool
 	IsProc                       bool
+	Attr                         uint64
+	Recursive                    bool
+	UserNs                       *byte
 }
 
 // ToSyscall convert Mount to SyscallPrams
@@ -40,6 +53,13 @@ func (m *Mount) ToSyscall() (*SyscallParams, error) {
 			return nil, err
 		}
 	}
+	var userNs *byte
+	if m.UserNs != "" {
+		userNs, err = syscall.BytePtrFromString(m.UserNs)
+		if err != nil {
+			return nil, err
+		}
+	}
 	prefix := pathPrefix(m.Target)
 	paths, err := arrayPtrFromStrings(prefix)
 	if err != nil {
@@ -53,6 +73,10 @@ func (m *Mount) ToSyscall() (*SyscallParams, error) {
 		Data:     data,
 		Prefixes: paths,
 		IsProc:   m.FsType == "proc",
+
+		Attr:      m.Attr,
+		Recursive: m.Recursive,
+		UserNs:    userNs,
 	}, nil
 }
 
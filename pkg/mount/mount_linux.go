@@ -5,6 +5,8 @@ import (
 	"os"
 	"path/filepath"
 	"syscall"
+
+	"golang.org/x/sys/unix"
 )
 
 // Mount calls mount syscall
@@ -12,6 +14,9 @@ func (m *Mount) Mount() error {
 	if err := ensureMountTargetExists(m.Source, m.Target); err != nil {
 		return fmt.Errorf("mkdir: %w", err)
 	}
+	if m.UserNs != "" {
+		return m.mountIDMapped()
+	}
 	if err := syscall.Mount(m.Source, m.Target, m.FsType, m.Flags, m.Data); err != nil {
 		return fmt.Errorf("mount: %w", err)
 	}
@@ -29,9 +34,54 @@ func (m *Mount) Mount() error {
 			return fmt.Errorf("remount: %w", err)
 		}
 	}
+	if m.Attr != 0 {
+		attr := unix.MountAttr{Attr_set: m.Attr}
+		if err := unix.MountSetattr(unix.AT_FDCWD, m.Target, m.attrFlags(), &attr); err != nil {
+			return fmt.Errorf("mount_setattr: %w", err)
+		}
+	}
 	return nil
 }
 
+// mountIDMapped clones the source tree and attaches it to the target with
+// the id mapping of the user namespace
+func (m *Mount) mountIDMapped() error {
+	flags := unix.OPEN_TREE_CLONE | unix.OPEN_TREE_CLOEXEC
+	if m.Flags&syscall.MS_REC == syscall.MS_REC {
+		flags |= unix.AT_RECURSIVE
+	}
+	tree, err := unix.OpenTree(unix.AT_FDCWD, m.Source, uint(flags))
+	if err != nil {
+		return fmt.Errorf("open_tree: %w", err)
+	}
+	defer unix.Close(tree)
+
+	ns, err := unix.Open(m.UserNs, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("open user namespace: %w", err)
+	}
+	defer unix.Close(ns)
+
+	attr := unix.MountAttr{
+		Attr_set:  m.Attr | unix.MOUNT_ATTR_IDMAP,
+		Userns_fd: uint64(ns),
+	}
+	if err := unix.MountSetattr(tree, "", m.attrFlags()|unix.AT_EMPTY_PATH, &attr); err != nil {
+		return fmt.Errorf("mount_setattr: %w", err)
+	}
+	if err := unix.MoveMount(tree, "", unix.AT_FDCWD, m.Target, unix.MOVE_MOUNT_F_EMPTY_PATH); err != nil {
+		return fmt.Errorf("move_mount: %w", err)
+	}
+	return nil
+}
+
+func (m *Mount) attrFlags() uint {
+	if m.Recursive {
+		return unix.AT_RECURSIVE
+	}
+	return 0
+}
+
 // IsBindMount returns if it is a bind mount
 func (m Mount) IsBindMount() bool {
 	return m.Flags&syscall.MS_BIND == syscall.MS_BIND
@@ -85,6 +135,9 @@ func (m Mount) String() string {
 		return fmt.Sprintf("tmpfs[%s]", m.Target)
 
 	case m.FsType == "proc":
+		if m.Data != "" {
+			return fmt.Sprintf("proc[%s,%s]", flag, m.Data)
+		}
 		return fmt.Sprintf("proc[%s]", flag)
 
 	default:
@@ -0,0 +1,38 @@
+package mount
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// NewUserNamespace creates a user namespace with the id mappings for idmapped
+// mounts, the namespace is kept alive by the returned file (e.g. use
+// /proc/self/fd/[fd] as Mount.UserNs)
+func NewUserNamespace(uidMappings, gidMappings []syscall.SysProcIDMap) (*os.File, error) {
+	// the child stops at execve by ptrace thus never runs and it is killed
+	// after the namespace is opened
+	p, err := os.StartProcess("/proc/self/exe", []string{"userns"}, &os.ProcAttr{
+		Sys: &syscall.SysProcAttr{
+			Cloneflags:  syscall.CLONE_NEWUSER,
+			UidMappings: uidMappings,
+			GidMappings: gidMappings,
+			Ptrace:      true,
+			Pdeathsig:   syscall.SIGKILL,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("mount: create user namespace: %w", err)
+	}
+	defer func() {
+		p.Kill()
+		p.Wait()
+	}()
+
+	f, err := os.Open("/proc/" + strconv.Itoa(p.Pid) + "/ns/user")
+	if err != nil {
+		return nil, fmt.Errorf("mount: open user namespace: %w", err)
+	}
+	return f, nil
+}
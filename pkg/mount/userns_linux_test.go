@@ -0,0 +1,31 @@
+package mount
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestNewUserNamespace(t *testing.T) {
+	f, err := NewUserNamespace(
+		[]syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Geteuid(), Size: 1}},
+		[]syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getegid(), Size: 1}},
+	)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer f.Close()
+
+	ns, err := os.Readlink("/proc/self/fd/" + strconv.Itoa(int(f.Fd())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	self, err := os.Readlink("/proc/self/ns/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns == self {
+		t.Errorf("expected new user namespace, got %s", ns)
+	}
+}
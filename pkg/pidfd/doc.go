@@ -0,0 +1,5 @@
+// Package pidfd provides interface to Linux pidfd to signal and wait a process
+// without racing against pid reuse.
+// Requires kernel >= 5.3 (pidfd_open), 5.4 (waitid P_PIDFD) and 6.9 for
+// signal the process group
+package pidfd
@@ -0,0 +1,86 @@
+package pidfd
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// SignalProcessGroup is the PIDFD_SIGNAL_PROCESS_GROUP flag of
+// pidfd_send_signal (kernel >= 6.9)
+const SignalProcessGroup = 0x4
+
+// statusOffset is the offset of si_status of siginfo for SIGCHLD. It is
+// after signo, errno, code (union aligned to pointer size), si_pid and si_uid
+const statusOffset = (12+ptrSize-1)&^(ptrSize-1) + 8
+
+const ptrSize = unsafe.Sizeof(uintptr(0))
+
+// si_code for SIGCHLD (not defined in unix package)
+const (
+	cldExited = 1
+	cldKilled = 2
+	cldDumped = 3
+)
+
+// Open opens a pidfd for the process. It is only race free if the process is
+// an unreaped child of the caller
+func Open(pid int) (int, error) {
+	fd, err := unix.PidfdOpen(pid, 0)
+	if err != nil {
+		return -1, fmt.Errorf("pidfd: pidfd_open: %w", err)
+	}
+	return fd, nil
+}
+
+// Kill sends the signal to the process referred by the pidfd. ESRCH is ignored
+// since the process have exited
+func Kill(fd int, sig unix.Signal) error {
+	if err := unix.PidfdSendSignal(fd, sig, nil, 0); err != nil && !errors.Is(err, unix.ESRCH) {
+		return fmt.Errorf("pidfd: pidfd_send_signal: %w", err)
+	}
+	return nil
+}
+
+// KillGroup sends the signal to the process group led by the process referred
+// by the pidfd. ESRCH is ignored since all process in the group have exited
+func KillGroup(fd int, sig unix.Signal) error {
+	if err := unix.PidfdSendSignal(fd, sig, nil, SignalProcessGroup); err != nil && !errors.Is(err, unix.ESRCH) {
+		return fmt.Errorf("pidfd: pidfd_send_signal: %w", err)
+	}
+	return nil
+}
+
+// Wait waits the process referred by the pidfd to exit and reaps it. The
+// result is converted to wait4 style wait status
+func Wait(fd int, rusage *unix.Rusage) (unix.WaitStatus, error) {
+	var info unix.Siginfo
+	for {
+		err := unix.Waitid(unix.P_PIDFD, fd, &info, unix.WEXITED, rusage)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("pidfd: waitid: %w", err)
+		}
+		return toWaitStatus(&info), nil
+	}
+}
+
+// toWaitStatus converts the waitid result to wait status
+func toWaitStatus(info *unix.Siginfo) unix.WaitStatus {
+	b := (*[unsafe.Sizeof(*info)]byte)(unsafe.Pointer(info))
+	status := unix.WaitStatus(binary.NativeEndian.Uint32(b[statusOffset:]))
+	switch info.Code {
+	case cldExited:
+		return status << 8
+	case cldKilled:
+		return status & 0x7f
+	case cldDumped:
+		return status&0x7f | 0x80
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package pidfd
+
+import (
+	"os/exec"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestWait(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		kill   bool
+		exited bool
+		code   int
+	}{
+		{"Exit 0", []string{"/bin/true"}, false, true, 0},
+		{"Exit 1", []string{"/bin/false"}, false, true, 1},
+		{"Killed", []string{"/bin/sleep", "10"}, true, false, int(unix.SIGKILL)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(tt.args[0], tt.args[1:]...)
+			if err := cmd.Start(); err != nil {
+				t.Fatal(err)
+			}
+			fd, err := Open(cmd.Process.Pid)
+			if err != nil {
+				t.Skip(err)
+			}
+			defer unix.Close(fd)
+
+			if tt.kill {
+				if err := Kill(fd, unix.SIGKILL); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var rusage unix.Rusage
+			ws, err := Wait(fd, &rusage)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ws.Exited() != tt.exited {
+				t.Fatalf("Exited() = %v, expected %v (%#x)", ws.Exited(), tt.exited, ws)
+			}
+			if tt.exited && ws.ExitStatus() != tt.code {
+				t.Errorf("ExitStatus() = %d, expected %d", ws.ExitStatus(), tt.code)
+			}
+			if !tt.exited && int(ws.Signal()) != tt.code {
+				t.Errorf("Signal() = %d, expected %d", ws.Signal(), tt.code)
+			}
+
+			// signal after reap should not hit any process
+			if err := Kill(fd, unix.SIGKILL); err != nil {
+				t.Errorf("Kill after reap: %v", err)
+			}
+		})
+	}
+}
@@ -1,5 +1,5 @@
 // Package pipe provides a wrapper to create a pipe and
-// collect at most max bytes from the reader side
+// collect at most max bytes (or the first and the last bytes) from the reader side
 package pipe
 
 import (
@@ -16,6 +16,9 @@ type Buffer struct {
 	Buffer *bytes.Buffer
 	Done   <-chan struct{}
 	Max    int64
+
+	// Overflow is closed when more than max bytes are written
+	Overflow <-chan struct{}
 }
 
 // NewPipe create a pipe with a goroutine to copy its read-end to writer
@@ -42,18 +45,41 @@ func NewPipe(writer io.Writer, n int64) (<-chan struct{}, *os.File, error) {
 // Notice: if rely on done for finish, w need be closed in parent process
 func NewBuffer(max int64) (*Buffer, error) {
 	buffer := new(bytes.Buffer)
-	done, w, err := NewPipe(buffer, max+1)
+	overflow := make(chan struct{})
+	done, w, err := NewPipe(&limitWriter{Writer: buffer, n: max, overflow: overflow}, max+1)
 	if err != nil {
 		return nil, err
 	}
 	return &Buffer{
-		W:      w,
-		Max:    max,
-		Buffer: buffer,
-		Done:   done,
+		W:        w,
+		Max:      max,
+		Buffer:   buffer,
+		Done:     done,
+		Overflow: overflow,
 	}, nil
 }
 
+// Exceeded returns whether more than max bytes are written, it is valid after done
+func (b *Buffer) Exceeded() bool {
+	return int64(b.Buffer.Len()) > b.Max
+}
+
+// limitWriter closes overflow once more than n bytes are written
+type limitWriter struct {
+	io.Writer
+	n        int64
+	overflow chan struct{}
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	n, err := l.Writer.Write(p)
+	if l.n >= 0 && int64(n) > l.n {
+		close(l.overflow)
+	}
+	l.n -= int64(n)
+	return n, err
+}
+
 func (b Buffer) String() string {
 	return fmt.Sprintf("Buffer[%d/%d]", b.Buffer.Len(), b.Max)
 }
@@ -99,3 +99,30 @@ func TestNewBuffer_DoneCloses(t *testing.T) {
 		t.Fatal("timeout waiting for Done channel")
 	}
 }
+
+func TestNewBuffer_Overflow(t *testing.T) {
+	const max = 4
+	buf, err := NewBuffer(max)
+	if err != nil {
+		t.Fatalf("NewBuffer error: %v", err)
+	}
+	defer buf.W.Close()
+
+	_, _ = buf.W.Write([]byte("test"))
+	select {
+	case <-buf.Overflow:
+		t.Fatal("unexpected overflow")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	_, _ = buf.W.Write([]byte("more"))
+	select {
+	case <-buf.Overflow:
+	case <-time.After(time.Second):
+		t.Fatal("overflow not signaled")
+	}
+	<-buf.Done
+	if !buf.Exceeded() {
+		t.Errorf("expected exceeded: %v", buf)
+	}
+}
@@ -0,0 +1,268 @@
+package pipe
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// CheckMode defines how the output is compared with the expected output
+type CheckMode int
+
+// Check modes
+const (
+	// CheckExact compares byte by byte
+	CheckExact CheckMode = iota
+	// CheckToken compares whitespace separated tokens
+	CheckToken
+	// CheckFloat compares tokens, numbers are equal within absolute or
+	// relative error Epsilon
+	CheckFloat
+)
+
+// tokenSlack is the extra length allowed for output token than the expected
+const tokenSlack = 32
+
+// ErrMismatch is returned by Checker.Write once the output mismatched
+var ErrMismatch = errors.New("pipe: output mismatch")
+
+// Checker is a writer that compares the written output against the expected
+// output as it arrives. Mismatch is closed on the first mismatch so that the
+// program could be stopped early (e.g. by runner.OutputCheck)
+type Checker struct {
+	Mode    CheckMode
+	Epsilon float64
+
+	expected *bufio.Reader
+	offset   atomic.Int64 // output bytes checked
+
+	// token state
+	inToken  bool
+	token    []byte
+	tokStart int64
+	expToken []byte
+
+	mu             sync.Mutex
+	mismatch       chan struct{}
+	mismatched     bool
+	mismatchOffset int64
+	err            error
+}
+
+// NewChecker creates a checker for the expected output
+func NewChecker(expected io.Reader, mode CheckMode, epsilon float64) *Checker {
+	return &Checker{
+		Mode:     mode,
+		Epsilon:  epsilon,
+		expected: bufio.NewReader(expected),
+		mismatch: make(chan struct{}),
+	}
+}
+
+// Write implements io.Writer, it returns ErrMismatch after the first mismatch
+func (c *Checker) Write(p []byte) (int, error) {
+	if c.Mismatched() {
+		return 0, ErrMismatch
+	}
+	for i, b := range p {
+		var ok bool
+		if c.Mode == CheckExact {
+			ok = c.checkByte(b)
+		} else {
+			ok = c.checkTokenByte(b)
+		}
+		c.offset.Add(1)
+		if !ok {
+			return i, ErrMismatch
+		}
+	}
+	return len(p), nil
+}
+
+// Finish checks the end of output against the expected output, it should be
+// called after the output is closed. It returns whether the output matched
+func (c *Checker) Finish() bool {
+	if c.Mismatched() {
+		return false
+	}
+	switch c.Mode {
+	case CheckExact:
+		if _, err := c.expected.ReadByte(); err != io.EOF {
+			c.setMismatch(c.offset.Load(), err)
+		}
+	default:
+		if c.inToken && !c.endToken() {
+			return false
+		}
+		if tok, err := c.nextExpectedToken(); len(tok) > 0 || err != io.EOF {
+			c.setMismatch(c.offset.Load(), err)
+		}
+	}
+	return !c.Mismatched()
+}
+
+// Mismatch returns a channel that is closed on the first mismatch
+func (c *Checker) Mismatch() <-chan struct{} {
+	return c.mismatch
+}
+
+// Mismatched returns whether the output mismatched
+func (c *Checker) Mismatched() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mismatched
+}
+
+// Result returns whether the output matched, and the output byte offset of
+// the first mismatch (start of the token for token modes)
+func (c *Checker) Result() (bool, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.mismatched, c.mismatchOffset
+}
+
+// Err returns the error reading the expected output, if any
+func (c *Checker) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *Checker) String() string {
+	ok, off := c.Result()
+	if ok {
+		return fmt.Sprintf("Checker[ok,%d]", c.offset.Load())
+	}
+	return fmt.Sprintf("Checker[mismatch at %d]", off)
+}
+
+func (c *Checker) setMismatch(offset int64, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.mismatched {
+		return
+	}
+	c.mismatched = true
+	c.mismatchOffset = offset
+	if err != io.EOF {
+		c.err = err
+	}
+	close(c.mismatch)
+}
+
+func (c *Checker) checkByte(b byte) bool {
+	e, err := c.expected.ReadByte()
+	if err != nil || e != b {
+		c.setMismatch(c.offset.Load(), err)
+		return false
+	}
+	return true
+}
+
+func (c *Checker) checkTokenByte(b byte) bool {
+	if isSpace(b) {
+		if c.inToken {
+			return c.endToken()
+		}
+		return true
+	}
+	if !c.inToken {
+		tok, err := c.nextExpectedToken()
+		if len(tok) == 0 {
+			c.setMismatch(c.offset.Load(), err)
+			return false
+		}
+		c.inToken = true
+		c.tokStart = c.offset.Load()
+		c.token = c.token[:0]
+		c.expToken = tok
+	}
+	c.token = append(c.token, b)
+	if len(c.token) > len(c.expToken)+tokenSlack {
+		c.setMismatch(c.tokStart, nil)
+		return false
+	}
+	return true
+}
+
+func (c *Checker) endToken() bool {
+	c.inToken = false
+	if !c.tokenEqual(c.token, c.expToken) {
+		c.setMismatch(c.tokStart, nil)
+		return false
+	}
+	return true
+}
+
+func (c *Checker) tokenEqual(out, exp []byte) bool {
+	if string(out) == string(exp) {
+		return true
+	}
+	if c.Mode != CheckFloat {
+		return false
+	}
+	o, err := strconv.ParseFloat(string(out), 64)
+	if err != nil {
+		return false
+	}
+	e, err := strconv.ParseFloat(string(exp), 64)
+	if err != nil || math.IsNaN(o) || math.IsNaN(e) {
+		return false
+	}
+	diff := math.Abs(o - e)
+	return diff <= c.Epsilon || diff <= c.Epsilon*math.Abs(e)
+}
+
+// nextExpectedToken reads the next whitespace separated token, empty at EOF
+func (c *Checker) nextExpectedToken() ([]byte, error) {
+	var tok []byte
+	for {
+		b, err := c.expected.ReadByte()
+		if err != nil {
+			return tok, err
+		}
+		if isSpace(b) {
+			if len(tok) > 0 {
+				return tok, nil
+			}
+			continue
+		}
+		tok = append(tok, b)
+	}
+}
+
+func isSpace(b byte) bool {
+	switch b {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return true
+	}
+	return false
+}
+
+// CheckerBuffer is used to create a writable pipe and check the output
+// written to it against the expected output
+type CheckerBuffer struct {
+	W       *os.File
+	Checker *Checker
+	Done    <-chan struct{}
+}
+
+// NewCheckerBuffer creates a os pipe that checks its content by checker,
+// caller need to close w and call Checker.Finish after done
+func NewCheckerBuffer(checker *Checker) (*CheckerBuffer, error) {
+	done, w, err := NewPipe(checker, math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+	return &CheckerBuffer{
+		W:       w,
+		Checker: checker,
+		Done:    done,
+	}, nil
+}
@@ -0,0 +1,79 @@
+package pipe
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChecker(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     CheckMode
+		expected string
+		writes   []string
+		ok       bool
+		offset   int64
+	}{
+		{"exact", CheckExact, "1 2\n", []string{"1 ", "2\n"}, true, 0},
+		{"exact diff", CheckExact, "1 2\n", []string{"1 3\n"}, false, 2},
+		{"exact short", CheckExact, "1 2\n", []string{"1 2"}, false, 3},
+		{"exact long", CheckExact, "1 2\n", []string{"1 2\n\n"}, false, 4},
+		{"token", CheckToken, "1 2\n3\n", []string{"  1\n", "2 ", "3"}, true, 0},
+		{"token split", CheckToken, "hello world", []string{"hel", "lo wor", "ld\n"}, true, 0},
+		{"token diff", CheckToken, "1 2 3", []string{"1 2 4"}, false, 4},
+		{"token short", CheckToken, "1 2 3", []string{"1 2\n"}, false, 4},
+		{"token long", CheckToken, "1 2", []string{"1 2 3"}, false, 4},
+		{"token prefix", CheckToken, "12", []string{"1", "2", "3"}, false, 0},
+		{"token too long", CheckToken, "1", []string{strings.Repeat("1", 100)}, false, 0},
+		{"float", CheckFloat, "0.3333 100\n", []string{"0.33331 1.00001e2"}, true, 0},
+		{"float diff", CheckFloat, "0.3333 1", []string{"0.3333 1.1"}, false, 7},
+		{"float word", CheckFloat, "yes", []string{"YES"}, false, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewChecker(strings.NewReader(tc.expected), tc.mode, 1e-4)
+			for _, w := range tc.writes {
+				if _, err := c.Write([]byte(w)); err != nil {
+					break
+				}
+			}
+			if ok := c.Finish(); ok != tc.ok {
+				t.Fatalf("Finish() = %v, want %v (%v)", ok, tc.ok, c)
+			}
+			if ok, off := c.Result(); !ok && off != tc.offset {
+				t.Errorf("offset = %d, want %d", off, tc.offset)
+			}
+			select {
+			case <-c.Mismatch():
+				if tc.ok {
+					t.Error("unexpected mismatch signal")
+				}
+			default:
+				if !tc.ok {
+					t.Error("mismatch not signaled")
+				}
+			}
+		})
+	}
+}
+
+func TestNewCheckerBuffer(t *testing.T) {
+	buf, err := NewCheckerBuffer(NewChecker(strings.NewReader("1\n2\n3\n"), CheckToken, 0))
+	if err != nil {
+		t.Fatalf("NewCheckerBuffer error: %v", err)
+	}
+	defer buf.W.Close()
+
+	// mismatch is signaled before the writer finishes
+	go io.Copy(buf.W, strings.NewReader("1\n5\n"+strings.Repeat("x\n", 1<<20)))
+	select {
+	case <-buf.Checker.Mismatch():
+	case <-time.After(time.Second):
+		t.Fatal("mismatch not signaled")
+	}
+	if ok, off := buf.Checker.Result(); ok || off != 2 {
+		t.Errorf("Result() = %v, %d, want false, 2", ok, off)
+	}
+}
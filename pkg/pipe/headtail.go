@@ -0,0 +1,121 @@
+package pipe
+
+import (
+	"fmt"
+	"math"
+	"os"
+)
+
+// HeadTail is a writer that keeps the first Head bytes and the last Tail
+// bytes written to it, and counts the total written bytes
+type HeadTail struct {
+	Head, Tail int
+
+	head  []byte
+	tail  []byte // ring buffer once it is full
+	pos   int    // oldest byte in tail when full
+	total int64
+}
+
+// NewHeadTail creates a writer that keeps head and tail bytes
+func NewHeadTail(head, tail int) *HeadTail {
+	return &HeadTail{
+		Head: head,
+		Tail: tail,
+		head: make([]byte, 0, head),
+		tail: make([]byte, 0, tail),
+	}
+}
+
+// Write implements io.Writer, it never fails
+func (h *HeadTail) Write(p []byte) (int, error) {
+	n := len(p)
+	h.total += int64(n)
+
+	if r := h.Head - len(h.head); r > 0 {
+		r = min(r, len(p))
+		h.head = append(h.head, p[:r]...)
+		p = p[r:]
+	}
+	if h.Tail <= 0 || len(p) == 0 {
+		return n, nil
+	}
+	// only the last Tail bytes matters
+	if len(p) >= h.Tail {
+		h.tail = append(h.tail[:0], p[len(p)-h.Tail:]...)
+		h.pos = 0
+		return n, nil
+	}
+	if r := h.Tail - len(h.tail); r > 0 {
+		r = min(r, len(p))
+		h.tail = append(h.tail, p[:r]...)
+		p = p[r:]
+	}
+	for len(p) > 0 {
+		c := copy(h.tail[h.pos:], p)
+		p = p[c:]
+		h.pos = (h.pos + c) % h.Tail
+	}
+	return n, nil
+}
+
+// HeadBytes returns the first bytes written
+func (h *HeadTail) HeadBytes() []byte {
+	return h.head
+}
+
+// TailBytes returns the last bytes written after head
+func (h *HeadTail) TailBytes() []byte {
+	ret := make([]byte, 0, len(h.tail))
+	ret = append(ret, h.tail[h.pos:]...)
+	return append(ret, h.tail[:h.pos]...)
+}
+
+// Bytes returns head and tail bytes, the skipped middle is omitted
+func (h *HeadTail) Bytes() []byte {
+	ret := make([]byte, 0, len(h.head)+len(h.tail))
+	ret = append(ret, h.head...)
+	return append(ret, h.TailBytes()...)
+}
+
+// Total returns the total number of bytes written
+func (h *HeadTail) Total() int64 {
+	return h.total
+}
+
+// Truncated returns whether some bytes between head and tail are discarded
+func (h *HeadTail) Truncated() bool {
+	return h.total > int64(len(h.head)+len(h.tail))
+}
+
+func (h *HeadTail) String() string {
+	return fmt.Sprintf("HeadTail[%d+%d/%d]", len(h.head), len(h.tail), h.total)
+}
+
+// HeadTailBuffer is used to create a writable pipe and keep the first and
+// the last bytes written to it
+type HeadTailBuffer struct {
+	W      *os.File
+	Buffer *HeadTail
+	Done   <-chan struct{}
+}
+
+// NewHeadTailBuffer creates a os pipe that keeps the first head bytes and
+// the last tail bytes, caller need to close w
+// Notice: if rely on done for finish, w need be closed in parent process
+func NewHeadTailBuffer(head, tail int) (*HeadTailBuffer, error) {
+	buffer := NewHeadTail(head, tail)
+	done, w, err := NewPipe(buffer, math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+	return &HeadTailBuffer{
+		W:      w,
+		Buffer: buffer,
+		Done:   done,
+	}, nil
+}
+
+func (b HeadTailBuffer) String() string {
+	return fmt.Sprintf("HeadTailBuffer[%d+%d/%d]", b.Buffer.Head, b.Buffer.Tail, b.Buffer.Total())
+}
@@ -0,0 +1,73 @@
+package pipe
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestHeadTail_Write(t *testing.T) {
+	tests := []struct {
+		name      string
+		head      int
+		tail      int
+		writes    []string
+		want      string
+		truncated bool
+	}{
+		{"short", 4, 4, []string{"abc"}, "abc", false},
+		{"exact", 4, 4, []string{"abcd", "efgh"}, "abcdefgh", false},
+		{"large write", 2, 3, []string{"abcdefghij"}, "abhij", true},
+		{"small writes", 2, 3, []string{"ab", "c", "de", "f", "gh", "i"}, "abghi", true},
+		{"wrap", 1, 4, []string{"a", "bcd", "ef", "ghi"}, "afghi", true},
+		{"no tail", 3, 0, []string{"abcdef"}, "abc", true},
+		{"no head", 0, 3, []string{"abcdef"}, "def", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHeadTail(tc.head, tc.tail)
+			var total int64
+			for _, w := range tc.writes {
+				n, err := h.Write([]byte(w))
+				if err != nil || n != len(w) {
+					t.Fatalf("Write = %d, %v", n, err)
+				}
+				total += int64(n)
+			}
+			if got := string(h.Bytes()); got != tc.want {
+				t.Errorf("Bytes() = %q, want %q", got, tc.want)
+			}
+			if h.Total() != total {
+				t.Errorf("Total() = %d, want %d", h.Total(), total)
+			}
+			if h.Truncated() != tc.truncated {
+				t.Errorf("Truncated() = %v, want %v", h.Truncated(), tc.truncated)
+			}
+		})
+	}
+}
+
+func TestNewHeadTailBuffer(t *testing.T) {
+	buf, err := NewHeadTailBuffer(5, 5)
+	if err != nil {
+		t.Fatalf("NewHeadTailBuffer error: %v", err)
+	}
+	defer buf.W.Close()
+
+	input := "start" + strings.Repeat("-", 1<<20) + "trace"
+	if _, err := io.Copy(buf.W, strings.NewReader(input)); err != nil {
+		t.Fatalf("Copy error: %v", err)
+	}
+	buf.W.Close()
+	<-buf.Done
+
+	if got := string(buf.Buffer.HeadBytes()); got != "start" {
+		t.Errorf("HeadBytes() = %q, want %q", got, "start")
+	}
+	if got := string(buf.Buffer.TailBytes()); got != "trace" {
+		t.Errorf("TailBytes() = %q, want %q", got, "trace")
+	}
+	if buf.Buffer.Total() != int64(len(input)) || !buf.Buffer.Truncated() {
+		t.Errorf("unexpected buffer: %v", buf.Buffer)
+	}
+}
@@ -0,0 +1,134 @@
+package pipe
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/tobiichi3227/go-sandbox/pkg/memfd"
+)
+
+// MemfdBuffer is used to create a writable pipe and splice at most max bytes
+// into a memfd in the kernel, so that large output does not go through the
+// go heap and could be passed to another process as fd
+type MemfdBuffer struct {
+	W    *os.File
+	Done <-chan struct{}
+	Max  int64
+
+	// Overflow is closed when more than max bytes are written
+	Overflow <-chan struct{}
+
+	file *os.File
+	size int64
+	err  error
+}
+
+// NewMemfdBuffer creates a os pipe and a memfd with name to collect its
+// content, caller need to close w
+// Notice: if rely on done for finish, w need be closed in parent process
+func NewMemfdBuffer(name string, max int64) (*MemfdBuffer, error) {
+	f, err := memfd.New(name)
+	if err != nil {
+		return nil, err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	done := make(chan struct{})
+	overflow := make(chan struct{})
+	b := &MemfdBuffer{
+		W:        w,
+		Done:     done,
+		Max:      max,
+		Overflow: overflow,
+		file:     f,
+	}
+	go func() {
+		b.size, b.err = spliceN(f, r, max+1)
+		if b.size > max {
+			close(overflow)
+		}
+		if b.err == nil {
+			b.err = b.seal()
+		}
+		if b.err != nil {
+			f.Close()
+		}
+		close(done)
+		// ensure no blocking / SIGPIPE on the other end
+		io.Copy(io.Discard, r)
+		r.Close()
+	}()
+	return b, nil
+}
+
+// File returns the sealed memfd after done, the caller owns the file
+func (b *MemfdBuffer) File() (*os.File, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.file, nil
+}
+
+// Size returns number of bytes kept in the memfd, it is valid after done
+func (b *MemfdBuffer) Size() int64 {
+	return min(b.size, b.Max)
+}
+
+// Exceeded returns whether more than max bytes are written, it is valid after done
+func (b *MemfdBuffer) Exceeded() bool {
+	return b.size > b.Max
+}
+
+func (b *MemfdBuffer) String() string {
+	return fmt.Sprintf("MemfdBuffer[%d/%d]", b.Size(), b.Max)
+}
+
+// seal truncates the memfd to max and seals it readonly
+func (b *MemfdBuffer) seal() error {
+	if b.size > b.Max {
+		if err := b.file.Truncate(b.Max); err != nil {
+			return fmt.Errorf("pipe: truncate memfd: %w", err)
+		}
+	}
+	return memfd.Seal(b.file)
+}
+
+// spliceN moves at most n bytes from pipe r to file w until EOF
+func spliceN(w *os.File, r *os.File, n int64) (int64, error) {
+	rc, err := r.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var (
+		total int64
+		serr  error
+	)
+	wfd := int(w.Fd())
+	for total < n {
+		var c int64
+		err = rc.Read(func(fd uintptr) bool {
+			c, serr = unix.Splice(int(fd), nil, wfd, nil, int(n-total), unix.SPLICE_F_MOVE|unix.SPLICE_F_NONBLOCK)
+			return serr != unix.EAGAIN
+		})
+		if err != nil {
+			return total, err
+		}
+		if serr == unix.EINTR {
+			continue
+		}
+		if serr != nil {
+			return total, fmt.Errorf("pipe: splice: %w", serr)
+		}
+		if c == 0 {
+			break
+		}
+		total += c
+	}
+	return total, nil
+}
@@ -0,0 +1,58 @@
+package pipe
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestNewMemfdBuffer(t *testing.T) {
+	tests := []struct {
+		name     string
+		max      int64
+		input    string
+		exceeded bool
+	}{
+		{"small", 16, "hello", false},
+		{"exact", 5, "hello", false},
+		{"exceeded", 4, "hello", true},
+		{"large", 1 << 20, strings.Repeat("0123456789", 1<<16), false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf, err := NewMemfdBuffer("test", tc.max)
+			if err != nil {
+				t.Fatalf("NewMemfdBuffer error: %v", err)
+			}
+			defer buf.W.Close()
+
+			if _, err := io.Copy(buf.W, strings.NewReader(tc.input)); err != nil {
+				t.Fatalf("Copy error: %v", err)
+			}
+			buf.W.Close()
+			<-buf.Done
+
+			if buf.Exceeded() != tc.exceeded {
+				t.Errorf("Exceeded() = %v, want %v", buf.Exceeded(), tc.exceeded)
+			}
+			f, err := buf.File()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			got, err := io.ReadAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tc.input[:min(int64(len(tc.input)), tc.max)]
+			if !bytes.Equal(got, []byte(want)) || buf.Size() != int64(len(want)) {
+				t.Errorf("content length = %d, size = %d, want %d", len(got), buf.Size(), len(want))
+			}
+			if _, err := f.Write([]byte("x")); err == nil {
+				t.Error("expected write to sealed memfd to fail")
+			}
+		})
+	}
+}
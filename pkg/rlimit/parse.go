@@ -0,0 +1,195 @@
+package rlimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// limit kinds for parsing and formatting
+const (
+	kindCount = iota
+	kindSize
+)
+
+// limitKeys are the keys accepted by Parse except cpu and core, in the order
+// of PrepareRLimit
+var limitKeys = []struct {
+	name  string
+	kind  int
+	field func(*RLimits) *uint64
+}{
+	{"data", kindSize, func(r *RLimits) *uint64 { return &r.Data }},
+	{"fsize", kindSize, func(r *RLimits) *uint64 { return &r.FileSize }},
+	{"stack", kindSize, func(r *RLimits) *uint64 { return &r.Stack }},
+	{"as", kindSize, func(r *RLimits) *uint64 { return &r.AddressSpace }},
+	{"nofile", kindCount, func(r *RLimits) *uint64 { return &r.OpenFile }},
+	{"nproc", kindCount, func(r *RLimits) *uint64 { return &r.Process }},
+	{"memlock", kindSize, func(r *RLimits) *uint64 { return &r.MemLock }},
+	{"msgqueue", kindSize, func(r *RLimits) *uint64 { return &r.MsgQueue }},
+	{"sigpending", kindCount, func(r *RLimits) *uint64 { return &r.SigPending }},
+	{"rtprio", kindCount, func(r *RLimits) *uint64 { return &r.RTPriority }},
+	{"rttime", kindCount, func(r *RLimits) *uint64 { return &r.RTTime }},
+	{"nice", kindCount, func(r *RLimits) *uint64 { return &r.Nice }},
+}
+
+// Parse parses comma separated limits like "cpu=1:3,stack=64m,nofile=256"
+//
+// cpu is in seconds and accepts soft:hard, sizes accept k / m / g suffix,
+// core only accepts 0 to disable core dump
+func Parse(s string) (RLimits, error) {
+	var r RLimits
+	if err := r.Set(s); err != nil {
+		return RLimits{}, err
+	}
+	return r, nil
+}
+
+// Set updates the limits specified in s in the format of Parse and validates
+// the result, it implements flag.Value together with String
+func (r *RLimits) Set(s string) error {
+	for _, kv := range strings.Split(s, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || v == "" {
+			return fmt.Errorf("rlimit: invalid limit %q", kv)
+		}
+		if err := r.set(strings.ToLower(k), v); err != nil {
+			return fmt.Errorf("rlimit: %s: %w", k, err)
+		}
+	}
+	return r.Validate()
+}
+
+func (r *RLimits) set(k, v string) error {
+	switch k {
+	case "cpu":
+		soft, hard, hasHard := strings.Cut(v, ":")
+		cpu, err := strconv.ParseUint(soft, 10, 64)
+		if err != nil {
+			return err
+		}
+		var cpuHard uint64
+		if hasHard {
+			if cpuHard, err = strconv.ParseUint(hard, 10, 64); err != nil {
+				return err
+			}
+		}
+		r.CPU, r.CPUHard = cpu, cpuHard
+		return nil
+
+	case "core":
+		if v != "0" {
+			return fmt.Errorf("only 0 is supported")
+		}
+		r.DisableCore = true
+		return nil
+	}
+	for _, l := range limitKeys {
+		if l.name != k {
+			continue
+		}
+		var (
+			n   uint64
+			err error
+		)
+		if l.kind == kindSize {
+			n, err = parseSize(v)
+		} else {
+			n, err = strconv.ParseUint(v, 10, 64)
+		}
+		if err != nil {
+			return err
+		}
+		*l.field(r) = n
+		return nil
+	}
+	return fmt.Errorf("unknown limit")
+}
+
+// Validate checks that the soft limits do not exceed the hard limits and the
+// priority ceilings are in range
+func (r *RLimits) Validate() error {
+	if r.CPUHard > 0 && r.CPUHard < r.CPU {
+		return fmt.Errorf("rlimit: cpu soft limit %d s exceeds hard limit %d s", r.CPU, r.CPUHard)
+	}
+	if r.RTPriority > 99 {
+		return fmt.Errorf("rlimit: rtprio %d out of range [1, 99]", r.RTPriority)
+	}
+	if r.Nice > 40 {
+		return fmt.Errorf("rlimit: nice %d out of range [1, 40]", r.Nice)
+	}
+	for _, rl := range r.PrepareRLimit() {
+		if rl.Rlim.Cur > rl.Rlim.Max {
+			return fmt.Errorf("rlimit: soft limit exceeds hard limit: %v", rl)
+		}
+	}
+	return nil
+}
+
+// Spec returns the limits in the format accepted by Parse
+func (r RLimits) Spec() string {
+	var s []string
+	if r.CPU > 0 || r.CPUHard > 0 {
+		if r.CPUHard > 0 {
+			s = append(s, fmt.Sprintf("cpu=%d:%d", r.CPU, r.CPUHard))
+		} else {
+			s = append(s, fmt.Sprintf("cpu=%d", r.CPU))
+		}
+	}
+	for _, l := range limitKeys {
+		v := *l.field(&r)
+		if v == 0 {
+			continue
+		}
+		if l.kind == kindSize {
+			s = append(s, l.name+"="+formatSize(v))
+		} else {
+			s = append(s, l.name+"="+strconv.FormatUint(v, 10))
+		}
+	}
+	if r.DisableCore {
+		s = append(s, "core=0")
+	}
+	return strings.Join(s, ",")
+}
+
+// parseSize parses size in bytes with optional k / m / g suffix
+func parseSize(v string) (uint64, error) {
+	var shift uint
+	switch v[len(v)-1] {
+	case 'k', 'K':
+		shift = 10
+	case 'm', 'M':
+		shift = 20
+	case 'g', 'G':
+		shift = 30
+	}
+	if shift > 0 {
+		v = v[:len(v)-1]
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxUint64>>shift {
+		return 0, fmt.Errorf("size %s overflows", v)
+	}
+	return n << shift, nil
+}
+
+func formatSize(v uint64) string {
+	for _, u := range []struct {
+		shift  uint
+		suffix string
+	}{{30, "g"}, {20, "m"}, {10, "k"}} {
+		if v%(1<<u.shift) == 0 {
+			return strconv.FormatUint(v>>u.shift, 10) + u.suffix
+		}
+	}
+	return strconv.FormatUint(v, 10)
+}
@@ -0,0 +1,112 @@
+package rlimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want RLimits
+	}{
+		{"empty", "", RLimits{}},
+		{"cpu", "cpu=1", RLimits{CPU: 1}},
+		{"cpu hard", "cpu=1:3", RLimits{CPU: 1, CPUHard: 3}},
+		{"sizes", "stack=64m, data=1g,fsize=512k,as=100", RLimits{Stack: 64 << 20, Data: 1 << 30, FileSize: 512 << 10, AddressSpace: 100}},
+		{"counts", "nofile=256,nproc=1,sigpending=8", RLimits{OpenFile: 256, Process: 1, SigPending: 8}},
+		{"core", "core=0", RLimits{DisableCore: true}},
+		{"linux", "memlock=64k,msgqueue=8k,rtprio=1,rttime=1000,NICE=20", RLimits{MemLock: 64 << 10, MsgQueue: 8 << 10, RTPriority: 1, RTTime: 1000, Nice: 20}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Parse(tc.s)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tc.s, err)
+			}
+			if got != tc.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tc.s, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParse_Error(t *testing.T) {
+	for _, s := range []string{
+		"stack",
+		"stack=",
+		"unknown=1",
+		"stack=1x",
+		"stack=-1",
+		"stack=k",
+		"stack=99999999999g",
+		"nofile=1k",
+		"core=1",
+		"cpu=3:1",
+		"cpu=1:x",
+		"rtprio=100",
+		"nice=41",
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) expected error", s)
+		}
+	}
+}
+
+func TestSpec_RoundTrip(t *testing.T) {
+	tests := []RLimits{
+		{},
+		{CPU: 1},
+		{CPU: 1, CPUHard: 3},
+		{CPU: 1, CPUHard: 2, Data: 1024, FileSize: 2048, Stack: 64 << 20, AddressSpace: 1 << 30, OpenFile: 16, DisableCore: true},
+		{Data: 1000, MemLock: 3 << 10, MsgQueue: 5 << 20},
+		{Process: 1, SigPending: 8, RTPriority: 99, RTTime: 1000, Nice: 40, DisableCore: true},
+	}
+	for _, rl := range tests {
+		s := rl.Spec()
+		got, err := Parse(s)
+		if err != nil {
+			t.Fatalf("Parse(%q) error: %v", s, err)
+		}
+		if got != rl {
+			t.Errorf("Parse(%q) = %+v, want %+v", s, got, rl)
+		}
+	}
+}
+
+func TestSpec(t *testing.T) {
+	rl := RLimits{CPU: 1, CPUHard: 3, Stack: 64 << 20, OpenFile: 256, Process: 1, DisableCore: true}
+	want := "cpu=1:3,stack=64m,nofile=256,nproc=1,core=0"
+	if got := rl.Spec(); got != want {
+		t.Errorf("Spec() = %q, want %q", got, want)
+	}
+}
+
+func TestRLimits_Set(t *testing.T) {
+	rl := RLimits{CPU: 1, Stack: 1 << 20}
+	if err := rl.Set("stack=8m,nofile=64"); err != nil {
+		t.Fatalf("Set error: %v", err)
+	}
+	want := RLimits{CPU: 1, Stack: 8 << 20, OpenFile: 64}
+	if rl != want {
+		t.Errorf("got %+v, want %+v", rl, want)
+	}
+}
+
+func TestCPUSeconds(t *testing.T) {
+	for _, tc := range []struct {
+		d    time.Duration
+		want uint64
+	}{
+		{0, 0},
+		{time.Millisecond, 1},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+		{3 * time.Second, 3},
+	} {
+		if got := CPUSeconds(tc.d); got != tc.want {
+			t.Errorf("CPUSeconds(%v) = %d, want %d", tc.d, got, tc.want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/tobiichi3227/go-sandbox/runner"
 )
@@ -19,6 +20,15 @@ type RLimits struct {
 	AddressSpace uint64 // in bytes
 	OpenFile     uint64 // count
 	DisableCore  bool   // set core to 0
+
+	// linux specific limits
+	Process    uint64 // count, for the real user id
+	MemLock    uint64 // in bytes
+	MsgQueue   uint64 // in bytes
+	SigPending uint64 // count
+	RTPriority uint64 // priority ceiling (1-99)
+	RTTime     uint64 // in us
+	Nice       uint64 // nice ceiling as 20 - value (1-40)
 }
 
 // RLimit is the resource limits defined by Linux setrlimit
@@ -84,6 +94,25 @@ func (r *RLimits) PrepareRLimit() []RLimit {
 			Rlim: getRlimit(0, 0),
 		})
 	}
+	for _, l := range []struct {
+		res int
+		v   uint64
+	}{
+		{resNProc, r.Process},
+		{resMemLock, r.MemLock},
+		{resMsgQueue, r.MsgQueue},
+		{resSigPending, r.SigPending},
+		{resRTPrio, r.RTPriority},
+		{resRTTime, r.RTTime},
+		{resNice, r.Nice},
+	} {
+		if l.v > 0 && l.res >= 0 {
+			ret = append(ret, RLimit{
+				Res:  l.res,
+				Rlim: getRlimit(l.v, l.v),
+			})
+		}
+	}
 	return ret
 }
 
@@ -94,6 +123,20 @@ func (r RLimit) String() string {
 		return fmt.Sprintf("CPU[%d s:%d s]", r.Rlim.Cur, r.Rlim.Max)
 	case syscall.RLIMIT_NOFILE:
 		return fmt.Sprintf("OpenFile[%d:%d]", r.Rlim.Cur, r.Rlim.Max)
+	case resNProc:
+		return fmt.Sprintf("Process[%d:%d]", r.Rlim.Cur, r.Rlim.Max)
+	case resSigPending:
+		return fmt.Sprintf("SigPending[%d:%d]", r.Rlim.Cur, r.Rlim.Max)
+	case resRTPrio:
+		return fmt.Sprintf("RTPriority[%d:%d]", r.Rlim.Cur, r.Rlim.Max)
+	case resRTTime:
+		return fmt.Sprintf("RTTime[%d us:%d us]", r.Rlim.Cur, r.Rlim.Max)
+	case resNice:
+		return fmt.Sprintf("Nice[%d:%d]", r.Rlim.Cur, r.Rlim.Max)
+	case resMemLock:
+		t = "MemLock"
+	case resMsgQueue:
+		t = "MsgQueue"
 	case syscall.RLIMIT_DATA:
 		t = "Data"
 	case syscall.RLIMIT_FSIZE:
@@ -120,3 +163,12 @@ func (r RLimits) String() string {
 	sb.WriteString("]")
 	return sb.String()
 }
+
+// CPUSeconds returns the RLIMIT_CPU value for time limit d, rounded up to
+// whole seconds so that it could serve as a backstop of a precise limit
+func CPUSeconds(d time.Duration) uint64 {
+	if d <= 0 {
+		return 0
+	}
+	return uint64((d + time.Second - 1) / time.Second)
+}
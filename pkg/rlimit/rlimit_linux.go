@@ -0,0 +1,14 @@
+package rlimit
+
+import "golang.org/x/sys/unix"
+
+// linux specific resources
+const (
+	resNProc      = unix.RLIMIT_NPROC
+	resMemLock    = unix.RLIMIT_MEMLOCK
+	resMsgQueue   = unix.RLIMIT_MSGQUEUE
+	resSigPending = unix.RLIMIT_SIGPENDING
+	resRTPrio     = unix.RLIMIT_RTPRIO
+	resRTTime     = unix.RLIMIT_RTTIME
+	resNice       = unix.RLIMIT_NICE
+)
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package rlimit
+
+// linux specific resources, negative values are not applied
+const (
+	resNProc = -1 - iota
+	resMemLock
+	resMsgQueue
+	resSigPending
+	resRTPrio
+	resRTTime
+	resNice
+)
@@ -5,6 +5,8 @@ package rlimit
 import (
 	"syscall"
 	"testing"
+
+	"golang.org/x/sys/unix"
 )
 
 func TestPrepareRLimit(t *testing.T) {
@@ -33,6 +35,11 @@ func TestPrepareRLimit(t *testing.T) {
 			rl:     RLimits{CPU: 1, CPUHard: 2, Data: 1024, FileSize: 2048, Stack: 4096, AddressSpace: 8192, OpenFile: 16, DisableCore: true},
 			expect: []int{syscall.RLIMIT_CPU, syscall.RLIMIT_DATA, syscall.RLIMIT_FSIZE, syscall.RLIMIT_STACK, syscall.RLIMIT_AS, syscall.RLIMIT_NOFILE, syscall.RLIMIT_CORE},
 		},
+		{
+			name:   "Linux specific",
+			rl:     RLimits{OpenFile: 16, Process: 1, MemLock: 4096, MsgQueue: 4096, SigPending: 8, RTPriority: 1, RTTime: 1000, Nice: 20},
+			expect: []int{syscall.RLIMIT_NOFILE, unix.RLIMIT_NPROC, unix.RLIMIT_MEMLOCK, unix.RLIMIT_MSGQUEUE, unix.RLIMIT_SIGPENDING, unix.RLIMIT_RTPRIO, unix.RLIMIT_RTTIME, unix.RLIMIT_NICE},
+		},
 		{
 			name:   "DisableCore only",
 			rl:     RLimits{DisableCore: true},
@@ -96,6 +103,21 @@ func TestRLimitString(t *testing.T) {
 			rl:   RLimit{Res: syscall.RLIMIT_CORE, Rlim: syscall.Rlimit{Cur: 0, Max: 0}},
 			want: "Core[0 B:0 B]",
 		},
+		{
+			name: "NPROC",
+			rl:   RLimit{Res: unix.RLIMIT_NPROC, Rlim: syscall.Rlimit{Cur: 1, Max: 1}},
+			want: "Process[1:1]",
+		},
+		{
+			name: "MEMLOCK",
+			rl:   RLimit{Res: unix.RLIMIT_MEMLOCK, Rlim: syscall.Rlimit{Cur: 1024, Max: 1024}},
+			want: "MemLock[1.0 KiB:1.0 KiB]",
+		},
+		{
+			name: "RTTIME",
+			rl:   RLimit{Res: unix.RLIMIT_RTTIME, Rlim: syscall.Rlimit{Cur: 10, Max: 10}},
+			want: "RTTime[10 us:10 us]",
+		},
 	}
 
 	for _, tt := range tests {
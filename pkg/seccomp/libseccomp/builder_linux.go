@@ -10,8 +10,8 @@ import (
 
 // Builder is used to build the filter
 type Builder struct {
-	Allow, Kill []string
-	Default     Action
+	Allow, Trace, Kill []string
+	Default            Action
 }
 
 // Build builds the filter
@@ -23,6 +23,10 @@ func (b *Builder) Build() (seccomp.Filter, error) {
 				Action: libseccomp.ActionAllow,
 				Names:  b.Allow,
 			},
+			{
+				Action: libseccomp.ActionTrace,
+				Names:  b.Trace,
+			},
 			{
 				Action: libseccomp.ActionKillProcess,
 				Names:  b.Kill,
@@ -19,3 +19,15 @@ func ToSyscallName(sysno uint) (string, error) {
 	}
 	return n, nil
 }
+
+// ToSyscallNo convert syscall name to syscallno
+func ToSyscallNo(name string) (uint, error) {
+	if errInfo != nil {
+		return 0, errInfo
+	}
+	n, ok := info.SyscallNames[name]
+	if !ok {
+		return 0, fmt.Errorf("syscall name does not exist: %s", name)
+	}
+	return uint(n), nil
+}
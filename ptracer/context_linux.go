@@ -12,6 +12,8 @@ type Context struct {
 	Pid int
 	// current reg context (platform dependent)
 	regs syscall.PtraceRegs
+	// freeze stops other tracees sharing the memory before read
+	freeze func()
 }
 
 var (
@@ -38,8 +40,22 @@ func getTrapContext(pid int) (*Context, error) {
 	}, nil
 }
 
+// NewContext creates a context for the syscall with the given number and
+// arguments without a trap, so that the syscall handlers can be tested with
+// paths stored in the memory of pid
+func NewContext(pid int, syscallNo uint, args ...uint) *Context {
+	var a [6]uint
+	copy(a[:], args)
+	c := &Context{Pid: pid}
+	c.setSyscall(syscallNo, a)
+	return c
+}
+
 // GetString get the string from process data segment
 func (c *Context) GetString(addr uintptr) string {
+	if c.freeze != nil {
+		c.freeze()
+	}
 	buff := make([]byte, syscall.PathMax)
 	if UseVMReadv {
 		if err := vmReadStr(c.Pid, addr, buff); err != nil {
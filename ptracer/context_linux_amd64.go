@@ -41,6 +41,12 @@ func (c *Context) Arg5() uint {
 	return uint(c.regs.R9)
 }
 
+func (c *Context) setSyscall(no uint, args [6]uint) {
+	c.regs.Orig_rax = uint64(no)
+	c.regs.Rdi, c.regs.Rsi, c.regs.Rdx = uint64(args[0]), uint64(args[1]), uint64(args[2])
+	c.regs.R10, c.regs.R8, c.regs.R9 = uint64(args[3]), uint64(args[4]), uint64(args[5])
+}
+
 // SetReturnValue set the return value if skip the syscall
 func (c *Context) SetReturnValue(retval int) {
 	c.regs.Rax = uint64(retval)
@@ -41,6 +41,14 @@ func (c *Context) Arg5() uint {
 	return uint(c.regs.Uregs[5]) //R5
 }
 
+func (c *Context) setSyscall(no uint, args [6]uint) {
+	c.regs.Uregs[7] = uint32(no)
+	for i, a := range args {
+		c.regs.Uregs[i] = uint32(a)
+	}
+	c.regs.Uregs[17] = uint32(args[0]) // Orig_R0
+}
+
 // SetReturnValue set the return value if skip the syscall
 func (c *Context) SetReturnValue(retval int) {
 	c.regs.Uregs[0] = uint32(retval) // R0
@@ -39,6 +39,13 @@ func (c *Context) Arg5() uint {
 	return uint(c.regs.Regs[5]) //R5
 }
 
+func (c *Context) setSyscall(no uint, args [6]uint) {
+	c.regs.Regs[8] = uint64(no)
+	for i, a := range args {
+		c.regs.Regs[i] = uint64(a)
+	}
+}
+
 // SetReturnValue set the return value if skip the syscall
 func (c *Context) SetReturnValue(retval int) {
 	c.regs.Regs[0] = uint64(retval) // R0
@@ -0,0 +1,244 @@
+package ptracer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	unix "golang.org/x/sys/unix"
+)
+
+const (
+	// kcmpVM is the KCMP_VM type of kcmp syscall (not defined in unix package)
+	kcmpVM = 1
+
+	// freezeTimeout is the maximum time the tracees stay frozen by a syscall
+	freezeTimeout = time.Second
+	// freezePollInterval is the maximum interval to poll the tracees while frozen
+	freezePollInterval = time.Millisecond
+)
+
+// errFreezeTimeout is returned by waitFrozen if the syscall does not exit
+// within freezeTimeout
+var errFreezeTimeout = errors.New("ptracer: syscall blocked while other tracees are frozen")
+
+// waitResult stores a wait4 result collected while freezing tracees
+type waitResult struct {
+	pid     int
+	wstatus unix.WaitStatus
+	rusage  unix.Rusage
+}
+
+// freeze stops all other tracees sharing the address space with pid, so that
+// the memory read by the handler cannot be modified before the kernel reads it
+// again. The tracees are resumed by unfreeze after the syscall exits.
+//
+// Tracees are stopped by SIGSTOP delivered through tkill. A tracee reports some
+// other stop before the SIGSTOP is queued as pending and SIGSTOP is suppressed
+// when it is delivered later. Tracees in uninterruptible sleep (e.g. vfork
+// parent) are not waited since they cannot return to user space without
+// delivering the SIGSTOP first.
+//
+// Tracees sharing the address space that report stops during the freeze (e.g.
+// threads created by a frozen tracee inside clone) are kept stopped as well.
+//
+// The freeze is bounded by freezeTimeout since the traced syscall may block
+// waiting for a frozen tracee (e.g. open a FIFO while the writer is frozen).
+// The trace fails instead of resuming the tracees before the syscall exits,
+// since the kernel may not have read the arguments yet (e.g. stalled by
+// userfaultfd).
+func (ph *ptraceHandle) freeze(pid int) {
+	if _, ok := ph.frozen[pid]; ok {
+		return
+	}
+	if len(ph.frozen) == 0 {
+		ph.frozenAt = time.Now()
+	}
+	ph.frozen[pid] = nil
+	for tid := range ph.traced {
+		if tid == pid || ph.held[tid] || ph.isPending(tid) || !sameVM(pid, tid) {
+			continue
+		}
+		// SIGSTOP sent before is not delivered yet
+		if ph.sigstop[tid] != 0 {
+			ph.sigstop[tid] = pid
+			continue
+		}
+		if err := tkill(tid, unix.SIGSTOP); err != nil {
+			ph.Handler.Debug("freeze: tkill failed: ", tid, err)
+			continue
+		}
+		ph.sigstop[tid] = pid
+		if s := procState(tid); s == 'D' || s == 'Z' {
+			ph.Handler.Debug("freeze: skip wait for ", tid, string(s))
+			continue
+		}
+
+		var r waitResult
+		for {
+			var err error
+			r.pid, err = unix.Wait4(tid, &r.wstatus, unix.WALL, &r.rusage)
+			if err == unix.EINTR {
+				continue
+			}
+			if err != nil {
+				ph.Handler.Debug("freeze: wait4 failed: ", tid, err)
+				r.pid = 0
+			}
+			break
+		}
+		switch {
+		case r.pid == 0:
+			delete(ph.sigstop, tid)
+		case r.wstatus.Stopped() && r.wstatus.StopSignal() == unix.SIGSTOP:
+			ph.hold(pid, tid)
+		default:
+			// stopped by other reason or exited, handle it after unfreeze
+			if !r.wstatus.Stopped() {
+				delete(ph.sigstop, tid)
+			}
+			ph.pending[pid] = append(ph.pending[pid], r)
+		}
+	}
+	ph.Handler.Debug("freeze: ", pid, ph.frozen[pid])
+}
+
+// hold records the tracee tid stopped by the freeze of pid
+func (ph *ptraceHandle) hold(pid, tid int) {
+	delete(ph.sigstop, tid)
+	ph.held[tid] = true
+	ph.frozen[pid] = append(ph.frozen[pid], tid)
+}
+
+// unfreeze resumes the tracees stopped by the freeze of pid
+func (ph *ptraceHandle) unfreeze(pid int) {
+	tids, ok := ph.frozen[pid]
+	if !ok {
+		return
+	}
+	ph.Handler.Debug("unfreeze: ", pid, tids)
+	for _, tid := range tids {
+		delete(ph.held, tid)
+		unix.PtraceCont(tid, 0)
+	}
+	// suppress the SIGSTOP delivered after unfreeze
+	for tid, p := range ph.sigstop {
+		if p == pid {
+			ph.sigstop[tid] = -1
+		}
+	}
+	ph.ready = append(ph.ready, ph.pending[pid]...)
+	delete(ph.pending, pid)
+	delete(ph.frozen, pid)
+}
+
+// waitFrozen polls the wait result while tracees are frozen, it returns
+// errFreezeTimeout after freezeTimeout since the freeze started and the
+// tracees are kept frozen
+func (ph *ptraceHandle) waitFrozen(wstatus *unix.WaitStatus, rusage *unix.Rusage) (int, error) {
+	deadline := ph.frozenAt.Add(freezeTimeout)
+	for d := time.Microsecond; ; d = min(2*d, freezePollInterval) {
+		pid, err := unix.Wait4(-ph.pgid, wstatus, unix.WALL|unix.WNOHANG, rusage)
+		if pid != 0 || err != nil {
+			return pid, err
+		}
+		if time.Now().After(deadline) {
+			ph.Handler.Debug("freeze: timeout ", ph.frozen)
+			return 0, errFreezeTimeout
+		}
+		time.Sleep(d)
+	}
+}
+
+// holdStopped keeps the stopped tracee sharing the address space with a
+// frozen tracee stopped until unfreeze. Newly traced tracees and the SIGSTOP
+// sent by freeze are held, and other stops are handled after unfreeze.
+func (ph *ptraceHandle) holdStopped(r waitResult) bool {
+	if !r.wstatus.Stopped() {
+		return false
+	}
+	p, ok := ph.freezer(r.pid)
+	if !ok {
+		return false
+	}
+	_, sigstop := ph.sigstop[r.pid]
+	switch {
+	case !ph.traced[r.pid]:
+		// handle reports the failure of set option
+		if err := setPtraceOption(r.pid); err != nil {
+			return false
+		}
+		ph.Handler.Debug("freeze: hold new tracee ", r.pid)
+		ph.traced[r.pid] = true
+		ph.hold(p, r.pid)
+	case sigstop && r.wstatus.StopSignal() == unix.SIGSTOP:
+		ph.hold(p, r.pid)
+	default:
+		ph.pending[p] = append(ph.pending[p], r)
+	}
+	return true
+}
+
+// freezer returns the frozen tracee sharing the address space with tid
+func (ph *ptraceHandle) freezer(tid int) (int, bool) {
+	if _, ok := ph.frozen[tid]; ok {
+		return 0, false
+	}
+	for p := range ph.frozen {
+		if sameVM(p, tid) {
+			return p, true
+		}
+	}
+	return 0, false
+}
+
+// isPending checks whether the tracee have unhandled wait result
+func (ph *ptraceHandle) isPending(tid int) bool {
+	for _, rs := range ph.pending {
+		for _, r := range rs {
+			if r.pid == tid {
+				return true
+			}
+		}
+	}
+	for _, r := range ph.ready {
+		if r.pid == tid {
+			return true
+		}
+	}
+	return false
+}
+
+// sameVM checks whether two processes share the same address space, assumes
+// shared if kcmp is not available
+func sameVM(pid1, pid2 int) bool {
+	r, _, errno := unix.Syscall6(unix.SYS_KCMP, uintptr(pid1), uintptr(pid2), kcmpVM, 0, 0, 0)
+	if errno != 0 {
+		return true
+	}
+	return r == 0
+}
+
+func tkill(tid int, sig unix.Signal) error {
+	_, _, errno := unix.RawSyscall(unix.SYS_TKILL, uintptr(tid), uintptr(sig), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// procState reads the process state from /proc/<pid>/stat
+func procState(pid int) byte {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0
+	}
+	// comm could contain ")", state is after the last one
+	i := bytes.LastIndexByte(b, ')')
+	if i < 0 || i+2 >= len(b) {
+		return 0
+	}
+	return b[i+2]
+}
@@ -1,8 +1,14 @@
 package ptracer
 
 import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
 	"syscall"
 	"unsafe"
+
+	"golang.org/x/sys/unix"
 )
 
 // ptrace constants
@@ -39,3 +45,29 @@ func ptraceArm64SetSyscall(pid int, syscallNo int) error {
 func ptraceArmSetSyscall(pid int, syscallNo int) error {
 	return ptrace(PTRACE_SET_SYSCALL, pid, 0, uintptr(syscallNo))
 }
+
+func ptraceGetSiginfo(pid int, info *unix.Siginfo) error {
+	return ptrace(syscall.PTRACE_GETSIGINFO, pid, 0, uintptr(unsafe.Pointer(info)))
+}
+
+// siginfoAddr returns si_addr of the fault signals, which follows signo,
+// errno and code aligned to pointer
+func siginfoAddr(info *unix.Siginfo) uintptr {
+	const off = (3*4 + unsafe.Sizeof(uintptr(0)) - 1) &^ (unsafe.Sizeof(uintptr(0)) - 1)
+	return *(*uintptr)(unsafe.Add(unsafe.Pointer(info), off))
+}
+
+// threadGroup returns the thread group id (process id) of the thread from
+// /proc/<tid>/status
+func threadGroup(tid int) (int, error) {
+	b, err := os.ReadFile("/proc/" + strconv.Itoa(tid) + "/status")
+	if err != nil {
+		return 0, err
+	}
+	for _, l := range bytes.Split(b, []byte{'\n'}) {
+		if v, ok := bytes.CutPrefix(l, []byte("Tgid:")); ok {
+			return strconv.Atoi(string(bytes.TrimSpace(v)))
+		}
+	}
+	return 0, fmt.Errorf("ptracer: no tgid in status of %d", tid)
+}
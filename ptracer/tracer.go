@@ -19,6 +19,10 @@ type Tracer struct {
 	Handler
 	Runner
 	runner.Limit
+
+	// FreezeOnLimit freezes the tracees before kill when the limit hit or the
+	// context is canceled
+	FreezeOnLimit *runner.FreezeOnLimit
 }
 
 // Runner represents the process runner
@@ -8,6 +8,7 @@ import (
 
 	unix "golang.org/x/sys/unix"
 
+	"github.com/tobiichi3227/go-sandbox/pkg/pidfd"
 	"github.com/tobiichi3227/go-sandbox/runner"
 )
 
@@ -27,21 +28,39 @@ func (t *Tracer) Trace(c context.Context) (result runner.Result) {
 		result.Error = err.Error()
 		return
 	}
-	return t.trace(c, pgid)
+	runner.ObserverFromContext(c).OnStart(pgid)
+	// the child is not reaped yet, thus the pidfd refers to it (-1 if failed)
+	pidFd, err := pidfd.Open(pgid)
+	if err != nil {
+		t.Handler.Debug("pidfd open failed: ", err)
+	} else {
+		defer unix.Close(pidFd)
+	}
+	return t.trace(c, pgid, pidFd)
 }
 
-func (t *Tracer) trace(c context.Context, pgid int) (result runner.Result) {
+func (t *Tracer) trace(c context.Context, pgid, pidFd int) (result runner.Result) {
 	cc, cancel := context.WithCancel(c)
-	defer cancel()
+	killDone := make(chan struct{})
+	observer := runner.ObserverFromContext(c)
 
 	// handle cancellation
 	go func() {
+		defer close(killDone)
 		<-cc.Done()
-		killAll(pgid)
+		// the parent context is canceled by the caller upon limit hit
+		if c.Err() != nil {
+			if c.Err() == context.DeadlineExceeded {
+				observer.OnLimitHit(runner.StatusTimeLimitExceeded)
+			}
+			t.FreezeOnLimit.Kill(func() { killAll(pgid, pidFd) })
+		} else {
+			killAll(pgid, pidFd)
+		}
 	}()
 
 	sTime := time.Now()
-	ph := newPtraceHandle(t, pgid)
+	ph := newPtraceHandle(t, pgid, observer)
 
 	// handler potential panic and tle
 	// also ensure processes was well terminated
@@ -51,8 +70,12 @@ func (t *Tracer) trace(c context.Context, pgid int) (result runner.Result) {
 			result.Status = runner.StatusRunnerError
 			result.Error = fmt.Sprintf("%v", err)
 		}
+		// ensure cancellation handler finished before reaping
+		cancel()
+		<-killDone
 		// kill all tracee upon return
-		killAll(pgid)
+		killAll(pgid, pidFd)
+		ph.killTraced()
 		collectZombie(pgid)
 		if !ph.fTime.IsZero() {
 			result.SetUpTime = ph.fTime.Sub(sTime)
@@ -68,7 +91,14 @@ func (t *Tracer) trace(c context.Context, pgid int) (result runner.Result) {
 			pid     int             // store pid of wait4 result
 			err     error
 		)
-		if ph.execved {
+		if len(ph.ready) > 0 && len(ph.frozen) == 0 {
+			// wait result collected during freeze
+			r := ph.ready[0]
+			ph.ready = ph.ready[1:]
+			pid, wstatus, rusage = r.pid, r.wstatus, r.rusage
+		} else if len(ph.frozen) > 0 {
+			pid, err = ph.waitFrozen(&wstatus, &rusage)
+		} else if ph.execved {
 			// Wait for all child in the process group
 			pid, err = unix.Wait4(-pgid, &wstatus, unix.WALL, &rusage)
 		} else {
@@ -79,6 +109,12 @@ func (t *Tracer) trace(c context.Context, pgid int) (result runner.Result) {
 			t.Handler.Debug("wait4 EINTR")
 			continue
 		}
+		// the syscall blocked by the frozen tracees cannot be checked safely
+		if err == errFreezeTimeout {
+			result.Status = runner.StatusDisallowedSyscall
+			result.Error = err.Error()
+			return
+		}
 		if err != nil {
 			t.Handler.Debug("wait4 failed: ", err)
 			result.Status = runner.StatusRunnerError
@@ -94,15 +130,25 @@ func (t *Tracer) trace(c context.Context, pgid int) (result runner.Result) {
 			result.Time = userTime
 			result.Memory = userMem
 			if curStatus != runner.StatusNormal {
+				observer.OnLimitHit(curStatus)
+				t.FreezeOnLimit.Kill(func() { killAll(pgid, pidFd) })
 				return
 			}
 		}
 
+		// keep stopped while a tracee sharing its memory is frozen
+		if ph.holdStopped(waitResult{pid: pid, wstatus: wstatus, rusage: rusage}) {
+			continue
+		}
+
 		status, exitStatus, errStr, finished := ph.handle(pid, wstatus)
 		if finished || status != runner.StatusNormal {
 			result.Status = status
 			result.ExitStatus = exitStatus
 			result.Error = errStr
+			if status == runner.StatusSignalled {
+				result.RuntimeError = ph.runtimeError
+			}
 			return
 		}
 	}
@@ -130,10 +176,49 @@ type ptraceHandle struct {
 	traced  map[int]bool
 	execved bool
 	fTime   time.Time
+
+	// frozen maps the tracee in traced syscall to the tracees stopped by it
+	frozen map[int][]int
+	// frozenAt is the time the first of the current freezes started
+	frozenAt time.Time
+	// held is the set of tracees stopped by freeze
+	held map[int]bool
+	// sigstop maps the tracee with undelivered SIGSTOP sent by freeze to the
+	// freezing tracee (-1 if unfreezed)
+	sigstop map[int]int
+	// pending stores the wait results collected by freeze and moved to ready
+	// after unfreeze
+	pending map[int][]waitResult
+	ready   []waitResult
+
+	// fault is the last kernel generated fault signal, since the faulting
+	// thread may not be leader
+	fault fault
+
+	observer runner.Observer
+	// runtimeError is the classified cause of the leader being signalled
+	runtimeError runner.RuntimeError
 }
 
-func newPtraceHandle(t *Tracer, pgid int) *ptraceHandle {
-	return &ptraceHandle{t, pgid, make(map[int]bool), false, time.Time{}}
+func newPtraceHandle(t *Tracer, pgid int, observer runner.Observer) *ptraceHandle {
+	return &ptraceHandle{
+		Tracer:   t,
+		pgid:     pgid,
+		observer: observer,
+		traced:   make(map[int]bool),
+		frozen:   make(map[int][]int),
+		held:     make(map[int]bool),
+		sigstop:  make(map[int]int),
+		pending:  make(map[int][]waitResult),
+	}
+}
+
+// release cleans up the states of the exited tracee
+func (ph *ptraceHandle) release(pid int) {
+	ph.unfreeze(pid)
+	delete(ph.traced, pid)
+	delete(ph.held, pid)
+	delete(ph.sigstop, pid)
 }
 
 func (ph *ptraceHandle) handle(pid int, wstatus unix.WaitStatus) (status runner.Status, exitStatus int, errStr string, finished bool) {
@@ -141,7 +226,7 @@ func (ph *ptraceHandle) handle(pid int, wstatus unix.WaitStatus) (status runner.
 	// check process status
 	switch {
 	case wstatus.Exited():
-		delete(ph.traced, pid)
+		ph.release(pid)
 		ph.Handler.Debug("process exited: ", pid, wstatus.ExitStatus())
 		if pid == ph.pgid {
 			finished = true
@@ -163,7 +248,14 @@ func (ph *ptraceHandle) handle(pid int, wstatus unix.WaitStatus) (status runner.
 		sig := wstatus.Signal()
 		ph.Handler.Debug("ptrace signaled: ", sig)
 		if pid == ph.pgid {
-			delete(ph.traced, pid)
+			if sig == ph.fault.sig && ph.fault.tgid == pid {
+				ph.runtimeError = ph.fault.cause
+			} else {
+				ph.runtimeError = runner.ClassifySignal(sig, 0)
+			}
+		}
+		ph.release(pid)
+		if pid == ph.pgid {
 			switch sig {
 			case unix.SIGXCPU, unix.SIGKILL:
 				status = runner.StatusTimeLimitExceeded
@@ -195,6 +287,24 @@ func (ph *ptraceHandle) handle(pid int, wstatus unix.WaitStatus) (status runner.
 		stopSig := wstatus.StopSignal()
 		// Check stop signal, if trap then check seccomp
 		switch stopSig {
+		// syscall exit of the tracee holding the freeze
+		case unix.SIGTRAP | 0x80:
+			ph.unfreeze(pid)
+			unix.PtraceCont(pid, 0)
+			return
+
+		// SIGSTOP sent by freeze
+		case unix.SIGSTOP:
+			if p, ok := ph.sigstop[pid]; ok {
+				if _, ok := ph.frozen[p]; ok {
+					ph.hold(p, pid)
+					return
+				}
+				delete(ph.sigstop, pid)
+				unix.PtraceCont(pid, 0)
+				return
+			}
+
 		case unix.SIGTRAP:
 			switch trapCause := wstatus.TrapCause(); trapCause {
 			case unix.PTRACE_EVENT_SECCOMP:
@@ -206,6 +316,12 @@ func (ph *ptraceHandle) handle(pid int, wstatus unix.WaitStatus) (status runner.
 						errStr = err.Error()
 						return
 					}
+					// keep others frozen until the kernel have read the
+					// syscall arguments
+					if _, ok := ph.frozen[pid]; ok {
+						unix.PtraceSyscall(pid, 0)
+						return
+					}
 				} else {
 					ph.Handler.Debug("ptrace seccomp before execve (should be the execve syscall)")
 				}
@@ -217,10 +333,18 @@ func (ph *ptraceHandle) handle(pid int, wstatus unix.WaitStatus) (status runner.
 			case unix.PTRACE_EVENT_FORK:
 				ph.Handler.Debug("ptrace stop fork")
 			case unix.PTRACE_EVENT_EXEC:
+				// non-leader thread calling execve takes over the leader pid
+				if msg, err := unix.PtraceGetEventMsg(pid); err == nil && int(msg) != pid {
+					if tids, ok := ph.frozen[int(msg)]; ok {
+						ph.frozen[pid] = tids
+						delete(ph.frozen, int(msg))
+					}
+				}
 				// forked tracee have successfully called execve
 				if !ph.execved {
 					ph.fTime = time.Now()
 					ph.execved = true
+					ph.observer.OnExec()
 				}
 				ph.Handler.Debug("ptrace stop exec")
 
@@ -237,12 +361,14 @@ func (ph *ptraceHandle) handle(pid int, wstatus unix.WaitStatus) (status runner.
 			status = runner.StatusOutputLimitExceeded
 		}
 		if status != runner.StatusNormal {
+			ph.observer.OnLimitHit(status)
 			return
 		}
 		// Likely encountered SIGSEGV (segment violation)
 		// Or compiler child exited
 		if stopSig != unix.SIGSTOP {
 			ph.Handler.Debug("ptrace unexpected stop signal: ", stopSig)
+			ph.classifyFault(pid, stopSig)
 		}
 		ph.Handler.Debug("ptrace stopped")
 		unix.PtraceCont(pid, int(stopSig))
@@ -250,6 +376,48 @@ func (ph *ptraceHandle) handle(pid int, wstatus unix.WaitStatus) (status runner.
 	return
 }
 
+// fault is the classified cause of the fault signal of the thread group
+type fault struct {
+	tgid  int
+	sig   unix.Signal
+	cause runner.RuntimeError
+}
+
+// classifyFault records the cause of the fault signal from its siginfo
+// before it is delivered
+func (ph *ptraceHandle) classifyFault(pid int, sig unix.Signal) {
+	tgid, err := threadGroup(pid)
+	if err != nil {
+		ph.Handler.Debug("get thread group failed: ", err)
+		return
+	}
+	// the same signal delivered later supersedes the fault, which must have
+	// been handled since the thread group is still alive
+	if tgid == ph.fault.tgid && sig == ph.fault.sig {
+		ph.fault = fault{}
+	}
+
+	var info unix.Siginfo
+	if err := ptraceGetSiginfo(pid, &info); err != nil {
+		ph.Handler.Debug("ptrace get siginfo failed: ", err)
+		return
+	}
+	// user sent signal (si_code <= 0) is classified by the signal alone
+	if info.Code <= 0 {
+		return
+	}
+	e := runner.ClassifySignal(sig, info.Code)
+	if e == runner.RuntimeErrorNone {
+		return
+	}
+	if e == runner.RuntimeErrorSegmentationFault {
+		if ok, err := runner.IsStackFault(pid, siginfoAddr(&info)); err == nil && ok {
+			e = runner.RuntimeErrorStackOverflow
+		}
+	}
+	ph.fault = fault{tgid: tgid, sig: sig, cause: e}
+}
+
 // handleTrap handles the seccomp trap including the custom handle
 func (ph *ptraceHandle) handleTrap(pid int) error {
 	ph.Handler.Debug("seccomp traced")
@@ -263,10 +431,13 @@ func (ph *ptraceHandle) handleTrap(pid int) error {
 		if err != nil {
 			return err
 		}
+		// freeze other tracees before read syscall arguments from memory
+		ctx.freeze = func() { ph.freeze(pid) }
 		act := ph.Handler.Handle(ctx)
 
 		switch act {
 		case TraceBan:
+			ph.unfreeze(pid)
 			// Set the syscallno to -1 and return value into register to skip syscall.
 			// https://www.kernel.org/doc/Documentation/prctl/pkg/seccomp_filter.txt
 			return ctx.skipSyscall()
@@ -281,15 +452,52 @@ func (ph *ptraceHandle) handleTrap(pid int) error {
 // set Ptrace option that set up seccomp, exit kill and all mult-process actions
 func setPtraceOption(pid int) error {
 	const ptraceFlags = unix.PTRACE_O_TRACESECCOMP | unix.PTRACE_O_EXITKILL | unix.PTRACE_O_TRACEFORK |
-		unix.PTRACE_O_TRACECLONE | unix.PTRACE_O_TRACEEXEC | unix.PTRACE_O_TRACEVFORK
+		unix.PTRACE_O_TRACECLONE | unix.PTRACE_O_TRACEEXEC | unix.PTRACE_O_TRACEVFORK |
+		unix.PTRACE_O_TRACESYSGOOD
 	return unix.PtraceSetOptions(pid, ptraceFlags)
 }
 
-// kill all tracee according to pids
-func killAll(pgid int) {
+// kill all tracee according to pids, signal the process group through pidfd
+// if possible to avoid hitting unrelated process after all tracee reaped
+func killAll(pgid, pidFd int) {
+	if pidFd >= 0 && pidfd.KillGroup(pidFd, unix.SIGKILL) == nil {
+		return
+	}
 	unix.Kill(-pgid, unix.SIGKILL)
 }
 
+// killTraced kills and reaps the remaining tracees, including the ones left
+// the process group by setsid (tracees cannot escape from tracing)
+func (ph *ptraceHandle) killTraced() {
+	for pid := range ph.traced {
+		unix.Kill(pid, unix.SIGKILL)
+	}
+	var wstatus unix.WaitStatus
+	for pid := range ph.traced {
+		// the leader is reaped after the other threads in its thread group
+		if pid == ph.pgid {
+			continue
+		}
+		for {
+			_, err := unix.Wait4(pid, &wstatus, unix.WALL, nil)
+			if err == unix.EINTR {
+				continue
+			}
+			if err != nil || wstatus.Exited() || wstatus.Signaled() {
+				break
+			}
+		}
+	}
+	// reap the leader together with the threads not traced yet (e.g. created
+	// during freeze)
+	for {
+		_, err := unix.Wait4(-ph.pgid, &wstatus, unix.WALL, nil)
+		if err != nil && err != unix.EINTR {
+			break
+		}
+	}
+}
+
 // collect died child processes
 func collectZombie(pgid int) {
 	var wstatus unix.WaitStatus
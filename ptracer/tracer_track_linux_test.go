@@ -0,0 +1,633 @@
+package ptracer
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/tobiichi3227/go-sandbox/pkg/forkexec"
+	"github.com/tobiichi3227/go-sandbox/pkg/seccomp/libseccomp"
+	"github.com/tobiichi3227/go-sandbox/runner"
+)
+
+const (
+	raceEnv       = "PTRACER_TEST_RACE"
+	raceAllowEnv  = "PTRACER_TEST_RACE_ALLOW"
+	raceBanEnv    = "PTRACER_TEST_RACE_BAN"
+	raceEscaped   = 3
+	raceIteration = 500
+
+	fifoEnv = "PTRACER_TEST_FIFO"
+
+	daemonEnv     = "PTRACER_TEST_DAEMON"
+	daemonPidEnv  = "PTRACER_TEST_DAEMON_PID"
+	daemonParent  = "parent"
+	daemonSleeper = "sleeper"
+)
+
+func TestMain(m *testing.M) {
+	if os.Getenv(raceEnv) == "1" {
+		raceOpen(os.Getenv(raceAllowEnv), os.Getenv(raceBanEnv))
+	}
+	if p := os.Getenv(fifoEnv); p != "" {
+		fifoOpen(p)
+	}
+	switch os.Getenv(daemonEnv) {
+	case daemonParent:
+		daemonize(os.Getenv(daemonPidEnv))
+	case daemonSleeper:
+		time.Sleep(time.Hour)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// raceOpen opens the allowed file while another thread keeps swapping the
+// path buffer with the banned file. It exits with raceEscaped if the banned
+// file was ever opened.
+func raceOpen(allow, ban string) {
+	buf := make([]byte, len(allow)+1)
+	copy(buf, allow)
+
+	var banStat syscall.Stat_t
+	if err := syscall.Stat(ban, &banStat); err != nil {
+		os.Exit(2)
+	}
+
+	go func() {
+		runtime.LockOSThread()
+		for i := 0; ; i++ {
+			if i%2 == 0 {
+				copy(buf, ban)
+			} else {
+				copy(buf, allow)
+			}
+		}
+	}()
+
+	for range raceIteration {
+		fd, _, errno := syscall.Syscall6(syscall.SYS_OPENAT, 0,
+			uintptr(unsafe.Pointer(&buf[0])), syscall.O_RDONLY, 0, 0, 0)
+		if errno != 0 {
+			continue
+		}
+		var st syscall.Stat_t
+		syscall.Fstat(int(fd), &st)
+		syscall.Close(int(fd))
+		if st.Dev == banStat.Dev && st.Ino == banStat.Ino {
+			os.Exit(raceEscaped)
+		}
+	}
+	os.Exit(0)
+}
+
+// fifoOpen opens the FIFO for read, which blocks until another thread opens
+// it for write after the read is trapped
+func fifoOpen(p string) {
+	if err := syscall.Mkfifo(p, 0o644); err != nil {
+		os.Exit(2)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		fd, err := syscall.Open(p, syscall.O_WRONLY, 0)
+		if err != nil {
+			os.Exit(2)
+		}
+		syscall.Close(fd)
+	}()
+	fd, err := syscall.Open(p, syscall.O_RDONLY, 0)
+	if err != nil {
+		os.Exit(2)
+	}
+	syscall.Close(fd)
+	os.Exit(0)
+}
+
+// daemonize starts a grandchild in a new session, writes its pid into the
+// file and exits without waiting for it
+func daemonize(pidFile string) {
+	cmd := exec.Command("/proc/self/exe", "-test.run=^$")
+	cmd.Env = []string{daemonEnv + "=" + daemonSleeper}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		os.Exit(2)
+	}
+	if err := os.WriteFile(pidFile, []byte(strconv.Itoa(cmd.Process.Pid)), 0o644); err != nil {
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
+
+type raceHandler struct {
+	ban string
+}
+
+func (h *raceHandler) Handle(ctx *Context) TraceAction {
+	p := ctx.GetString(uintptr(ctx.Arg1()))
+	// widen the window between the check and the syscall
+	time.Sleep(100 * time.Microsecond)
+	if p == h.ban {
+		return TraceBan
+	}
+	return TraceAllow
+}
+
+func (h *raceHandler) Debug(v ...interface{}) {}
+
+// TestTrace_PathRace checks that the path checked by the handler is the path
+// used by the kernel even if another thread modifies it concurrently
+func TestTrace_PathRace(t *testing.T) {
+	dir := t.TempDir()
+	allow := filepath.Join(dir, "allow")
+	ban := filepath.Join(dir, "block")
+	for _, p := range []string{allow, ban} {
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := libseccomp.Builder{
+		Trace:   []string{"openat"},
+		Default: libseccomp.ActionAllow,
+	}
+	filter, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &forkexec.Runner{
+		Args:    []string{exe, "-test.run=^$"},
+		Env:     []string{raceEnv + "=1", raceAllowEnv + "=" + allow, raceBanEnv + "=" + ban},
+		Files:   []uintptr{0, 1, 2},
+		Seccomp: filter.SockFprog(),
+		Ptrace:  true,
+	}
+	tracer := Tracer{
+		Handler: &raceHandler{ban: ban},
+		Runner:  r,
+		Limit: runner.Limit{
+			TimeLimit:   10 * time.Second,
+			MemoryLimit: 1 << 30,
+		},
+	}
+	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result := tracer.Trace(c)
+	switch result.Status {
+	case runner.StatusNormal:
+	default:
+		t.Errorf("unexpected result (%d escaped): %v", raceEscaped, result)
+	}
+}
+
+// raceCloneSrc opens the allowed file while threads keep being created to
+// swap the path buffer with the banned file, so that some threads are created
+// during the freeze. It exits with raceEscaped if the banned file was ever
+// opened.
+const raceCloneSrc = `#include <fcntl.h>
+#include <pthread.h>
+#include <string.h>
+#include <sys/stat.h>
+#include <unistd.h>
+
+static char buf[4096];
+static const char *allow, *ban;
+
+static void *swap(void *arg) {
+	for (int i = 0; i < 1000; i++)
+		strcpy(buf, i % 2 ? allow : ban);
+	return 0;
+}
+
+static void *spawn(void *arg) {
+	for (;;) {
+		pthread_t t;
+		if (pthread_create(&t, 0, swap, 0) == 0)
+			pthread_join(t, 0);
+	}
+}
+
+int main(int argc, char **argv) {
+	struct stat bs, st;
+	pthread_t t;
+	allow = argv[1];
+	ban = argv[2];
+	if (stat(ban, &bs))
+		return 2;
+	strcpy(buf, allow);
+	for (int i = 0; i < 4; i++)
+		pthread_create(&t, 0, spawn, 0);
+	for (int i = 0; i < 2000; i++) {
+		int fd = open(buf, O_RDONLY);
+		if (fd < 0)
+			continue;
+		fstat(fd, &st);
+		close(fd);
+		if (st.st_dev == bs.st_dev && st.st_ino == bs.st_ino)
+			_exit(3);
+	}
+	_exit(0);
+}
+`
+
+// TestTrace_PathRaceClone checks that the threads created while the other
+// tracees are frozen cannot modify the path checked by the handler
+func TestTrace_PathRaceClone(t *testing.T) {
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("cc not found")
+	}
+	dir := t.TempDir()
+	allow := filepath.Join(dir, "allow")
+	ban := filepath.Join(dir, "block")
+	for _, p := range []string{allow, ban} {
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src := filepath.Join(dir, "race.c")
+	bin := filepath.Join(dir, "race")
+	if err := os.WriteFile(src, []byte(raceCloneSrc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command(cc, "-O0", "-pthread", "-o", bin, src).CombinedOutput(); err != nil {
+		t.Skipf("compile: %v: %s", err, out)
+	}
+
+	b := libseccomp.Builder{
+		Trace:   []string{"openat"},
+		Default: libseccomp.ActionAllow,
+	}
+	filter, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := Tracer{
+		Handler: &raceHandler{ban: ban},
+		Runner: &forkexec.Runner{
+			Args:    []string{bin, allow, ban},
+			Files:   []uintptr{0, 1, 2},
+			Seccomp: filter.SockFprog(),
+			Ptrace:  true,
+		},
+		Limit: runner.Limit{
+			TimeLimit:   10 * time.Second,
+			MemoryLimit: 1 << 30,
+		},
+	}
+	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result := tracer.Trace(c)
+	if result.Status != runner.StatusNormal {
+		t.Errorf("unexpected result (%d escaped): %v", raceEscaped, result)
+	}
+}
+
+// readOnlyHandler reads the path of openat for read only, which freezes the
+// other tracees
+type readOnlyHandler struct{}
+
+func (readOnlyHandler) Handle(ctx *Context) TraceAction {
+	if ctx.Arg2()&syscall.O_ACCMODE == syscall.O_RDONLY {
+		ctx.GetString(uintptr(ctx.Arg1()))
+	}
+	return TraceAllow
+}
+
+func (readOnlyHandler) Debug(v ...interface{}) {}
+
+// TestTrace_FreezeBlockingSyscall checks that the syscall blocked by a frozen
+// tracee fails the trace instead of deadlock or resuming the frozen tracees
+func TestTrace_FreezeBlockingSyscall(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := libseccomp.Builder{
+		Trace:   []string{"openat"},
+		Default: libseccomp.ActionAllow,
+	}
+	filter, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &forkexec.Runner{
+		Args:    []string{exe, "-test.run=^$"},
+		Env:     []string{fifoEnv + "=" + filepath.Join(t.TempDir(), "fifo")},
+		Files:   []uintptr{0, 1, 2},
+		Seccomp: filter.SockFprog(),
+		Ptrace:  true,
+	}
+	tracer := Tracer{
+		Handler: readOnlyHandler{},
+		Runner:  r,
+		Limit: runner.Limit{
+			TimeLimit:   10 * time.Second,
+			MemoryLimit: 1 << 30,
+		},
+	}
+	c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	result := tracer.Trace(c)
+	if result.Status != runner.StatusDisallowedSyscall {
+		t.Errorf("unexpected result: %v", result)
+	}
+	if d := time.Since(start); d > 2*freezeTimeout {
+		t.Errorf("freeze is not bounded: %v", d)
+	}
+}
+
+type allowHandler struct{}
+
+func (allowHandler) Handle(ctx *Context) TraceAction {
+	return TraceAllow
+}
+
+func (allowHandler) Debug(v ...interface{}) {}
+
+// TestTrace_KillDaemon checks that the grandchild escaped from the process
+// group by setsid does not survive the trace
+func TestTrace_KillDaemon(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := libseccomp.Builder{
+		Default: libseccomp.ActionAllow,
+	}
+	filter, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &forkexec.Runner{
+		Args:    []string{exe, "-test.run=^$"},
+		Env:     []string{daemonEnv + "=" + daemonParent, daemonPidEnv + "=" + pidFile},
+		Files:   []uintptr{0, 1, 2},
+		Seccomp: filter.SockFprog(),
+		Ptrace:  true,
+	}
+	tracer := Tracer{
+		Handler: allowHandler{},
+		Runner:  r,
+		Limit: runner.Limit{
+			TimeLimit:   10 * time.Second,
+			MemoryLimit: 1 << 30,
+		},
+	}
+	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result := tracer.Trace(c)
+	if result.Status != runner.StatusNormal {
+		t.Fatalf("unexpected result: %v", result)
+	}
+	content, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := strconv.Atoi(string(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the zombie may wait to be reaped by its new parent
+	if s := procState(pid); s != 0 && s != 'Z' {
+		syscall.Kill(pid, syscall.SIGKILL)
+		t.Errorf("daemon %d survived with state %c", pid, s)
+	}
+}
+
+type recordFreezer struct {
+	calls []string
+}
+
+func (f *recordFreezer) Freeze() error {
+	f.calls = append(f.calls, "freeze")
+	return nil
+}
+
+func (f *recordFreezer) Thaw() error {
+	f.calls = append(f.calls, "thaw")
+	return nil
+}
+
+// TestTrace_FreezeOnLimit checks that the tracees are frozen before kill when
+// the context is canceled
+func TestTrace_FreezeOnLimit(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := libseccomp.Builder{
+		Default: libseccomp.ActionAllow,
+	}
+	filter, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &recordFreezer{}
+	tracer := Tracer{
+		Handler: allowHandler{},
+		Runner: &forkexec.Runner{
+			Args:    []string{exe, "-test.run=^$"},
+			Env:     []string{daemonEnv + "=" + daemonSleeper},
+			Files:   []uintptr{0, 1, 2},
+			Seccomp: filter.SockFprog(),
+			Ptrace:  true,
+		},
+		Limit: runner.Limit{
+			TimeLimit:   10 * time.Second,
+			MemoryLimit: 1 << 30,
+		},
+		FreezeOnLimit: &runner.FreezeOnLimit{
+			Freezer: f,
+			OnFrozen: func() {
+				f.calls = append(f.calls, "frozen")
+			},
+		},
+	}
+	c, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	result := tracer.Trace(c)
+	if result.Status != runner.StatusTimeLimitExceeded {
+		t.Errorf("unexpected result: %v", result)
+	}
+	if got := strings.Join(f.calls, ","); got != "freeze,frozen,thaw" {
+		t.Errorf("unexpected freezer calls: %s", got)
+	}
+}
+
+func traceRuntimeError(t *testing.T, args []string) runner.Result {
+	t.Helper()
+	b := libseccomp.Builder{
+		Default: libseccomp.ActionAllow,
+	}
+	filter, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := Tracer{
+		Handler: allowHandler{},
+		Runner: &forkexec.Runner{
+			Args:    args,
+			Files:   []uintptr{0, 1, 2},
+			Seccomp: filter.SockFprog(),
+			Ptrace:  true,
+		},
+		Limit: runner.Limit{
+			TimeLimit:   10 * time.Second,
+			MemoryLimit: 1 << 30,
+		},
+	}
+	c, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return tracer.Trace(c)
+}
+
+func TestTrace_RuntimeError(t *testing.T) {
+	tests := []struct {
+		name   string
+		signal string
+		want   runner.RuntimeError
+	}{
+		{"abort", "ABRT", runner.RuntimeErrorAbort},
+		{"segv", "SEGV", runner.RuntimeErrorSegmentationFault},
+		{"fpe", "FPE", runner.RuntimeErrorFloatingPoint},
+		{"bus", "BUS", runner.RuntimeErrorBusError},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := traceRuntimeError(t, []string{"/bin/sh", "-c", "kill -" + tc.signal + " $$"})
+			if result.Status != runner.StatusSignalled || result.RuntimeError != tc.want {
+				t.Errorf("got %v (%v), want %v", result, result.RuntimeError, tc.want)
+			}
+		})
+	}
+}
+
+func TestTrace_RuntimeErrorFault(t *testing.T) {
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("cc not found")
+	}
+	tests := []struct {
+		name string
+		src  string
+		want runner.RuntimeError
+	}{
+		{"stack overflow", "int f(int n) { volatile char b[1024]; b[0] = n; return f(n + 1) + b[0]; }\nint main() { return f(0); }\n", runner.RuntimeErrorStackOverflow},
+		{"null", "int main() { return *(volatile int *)0; }\n", runner.RuntimeErrorSegmentationFault},
+		{"divide by zero", "int main() { volatile int a = 7, z = 0; return a / z; }\n", runner.RuntimeErrorDivideByZero},
+		{"handled fault", "#include <setjmp.h>\n#include <signal.h>\nstatic sigjmp_buf env;\nstatic void h(int s) { siglongjmp(env, 1); }\nint main() { volatile int a = 7, z = 0; signal(SIGFPE, h); if (!sigsetjmp(env, 1)) a /= z; signal(SIGFPE, SIG_DFL); raise(SIGFPE); return 0; }\n", runner.RuntimeErrorFloatingPoint},
+		{"child fault", "#include <setjmp.h>\n#include <signal.h>\n#include <unistd.h>\nstatic sigjmp_buf env;\nstatic void h(int s) { siglongjmp(env, 1); }\nint main() { volatile int a = 7, z = 0; if (fork() == 0) { signal(SIGFPE, h); sigsetjmp(env, 1); a /= z; } usleep(20000); raise(SIGFPE); return 0; }\n", runner.RuntimeErrorFloatingPoint},
+	}
+	dir := t.TempDir()
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			src := filepath.Join(dir, strconv.Itoa(i)+".c")
+			bin := filepath.Join(dir, strconv.Itoa(i))
+			if err := os.WriteFile(src, []byte(tc.src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if out, err := exec.Command(cc, "-O0", "-o", bin, src).CombinedOutput(); err != nil {
+				t.Skipf("compile: %v: %s", err, out)
+			}
+			result := traceRuntimeError(t, []string{bin})
+			if result.Status != runner.StatusSignalled || result.RuntimeError != tc.want {
+				t.Errorf("got %v (%v), want %v", result, result.RuntimeError, tc.want)
+			}
+		})
+	}
+}
+
+type recordObserver struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (o *recordObserver) record(e string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, e)
+}
+
+func (o *recordObserver) OnStart(pid int) {
+	if pid > 0 {
+		o.record("start")
+	}
+}
+
+func (o *recordObserver) OnExec() { o.record("exec") }
+
+func (o *recordObserver) OnLimitHit(status runner.Status) { o.record("limit " + status.Name()) }
+
+func (o *recordObserver) OnExit(result runner.Result) { o.record("exit " + result.Status.Name()) }
+
+func TestTrace_Observer(t *testing.T) {
+	b := libseccomp.Builder{
+		Default: libseccomp.ActionAllow,
+	}
+	filter, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		args    []string
+		timeout time.Duration
+		want    []string
+	}{
+		{"normal", []string{"/bin/true"}, 10 * time.Second, []string{"start", "exec"}},
+		{"deadline", []string{"/bin/sleep", "10"}, 100 * time.Millisecond, []string{"start", "exec", "limit TLE"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer := Tracer{
+				Handler: allowHandler{},
+				Runner: &forkexec.Runner{
+					Args:    tc.args,
+					Files:   []uintptr{0, 1, 2},
+					Seccomp: filter.SockFprog(),
+					Ptrace:  true,
+				},
+				Limit: runner.Limit{
+					TimeLimit:   10 * time.Second,
+					MemoryLimit: 1 << 30,
+				},
+			}
+			o := &recordObserver{}
+			c, cancel := context.WithTimeout(runner.WithObserver(context.Background(), o), tc.timeout)
+			defer cancel()
+			tracer.Trace(c)
+
+			o.mu.Lock()
+			defer o.mu.Unlock()
+			if strings.Join(o.events, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("got events %v, want %v", o.events, tc.want)
+			}
+		})
+	}
+}
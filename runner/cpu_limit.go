@@ -0,0 +1,79 @@
+package runner
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCPUCheckInterval is the default interval to poll the CPU usage
+const DefaultCPUCheckInterval = 10 * time.Millisecond
+
+// CPULimit wraps a Runner to enforce CPU time limit with sub-second
+// precision by polling the CPU usage, since RLIMIT_CPU is in whole seconds.
+// RLIMIT_CPU should still be set to the ceiling of the limit as a backstop
+// (e.g. rlimit.CPUSeconds)
+//
+// Result.Time is kept as the user CPU time reported by the runner, the usage
+// polled (which may include system time) only decides the status
+type CPULimit struct {
+	Runner
+	TimeLimit time.Duration
+
+	// Usage returns the current CPU usage of the program (e.g. the cgroup
+	// cpu usage or ProcCPUUsage), errors are ignored until next poll
+	Usage func() (time.Duration, error)
+
+	// Interval is the polling interval, DefaultCPUCheckInterval if 0
+	Interval time.Duration
+}
+
+// Run cancels the underlying runner once the CPU usage exceeds the limit and
+// overrides the status
+func (l *CPULimit) Run(c context.Context) Result {
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	interval := l.Interval
+	if interval <= 0 {
+		interval = DefaultCPUCheckInterval
+	}
+
+	var exceeded atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			u, err := l.Usage()
+			if err != nil {
+				continue
+			}
+			if u > l.TimeLimit {
+				exceeded.Store(true)
+				ObserverFromContext(c).OnLimitHit(StatusTimeLimitExceeded)
+				cancel()
+				return
+			}
+		}
+	}()
+	result := l.Runner.Run(ctx)
+
+	// ensure usage is not polled after the runner returned
+	cancel()
+	<-done
+
+	if exceeded.Load() || result.Time > l.TimeLimit {
+		switch result.Status {
+		case StatusNormal, StatusNonzeroExitStatus, StatusSignalled, StatusTimeLimitExceeded:
+			result.Status = StatusTimeLimitExceeded
+		}
+	}
+	return result
+}
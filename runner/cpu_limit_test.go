@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCPULimit(t *testing.T) {
+	const limit = 50 * time.Millisecond
+	tests := []struct {
+		name     string
+		step     time.Duration // usage increased by each poll
+		result   Result        // result of the runner stub if not canceled
+		expected Status
+	}{
+		{"Within", 0, Result{Status: StatusNormal, Time: limit}, StatusNormal},
+		{"Exceeded", 10 * time.Millisecond, Result{Status: StatusNormal}, StatusTimeLimitExceeded},
+		{"ExceededAfterExit", 0, Result{Status: StatusNonzeroExitStatus, Time: 2 * limit}, StatusTimeLimitExceeded},
+		{"RunnerError", 0, Result{Status: StatusRunnerError, Time: 2 * limit}, StatusRunnerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var usage atomic.Int64
+			r := runFunc(func(c context.Context) Result {
+				if tt.step == 0 {
+					return tt.result
+				}
+				// the program runs until killed
+				<-c.Done()
+				return Result{Status: StatusSignalled, Time: limit / 2}
+			})
+			l := &CPULimit{
+				Runner:    r,
+				TimeLimit: limit,
+				Usage: func() (time.Duration, error) {
+					return time.Duration(usage.Add(int64(tt.step))), nil
+				},
+				Interval: time.Millisecond,
+			}
+			c, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			result := l.Run(c)
+			if result.Status != tt.expected {
+				t.Errorf("status = %v, expected %v", result.Status, tt.expected)
+			}
+			// the time reported by the runner is kept
+			if tt.step > 0 && result.Time != limit/2 {
+				t.Errorf("time = %v, expected %v", result.Time, limit/2)
+			}
+			if c.Err() != nil {
+				t.Error("program is not killed by CPU limit")
+			}
+		})
+	}
+}
@@ -10,12 +10,18 @@
 //	    Resource Limit Exceeded (Time / Memory / Output)
 //	    Unauthorized Access (Disallowed Syscall)
 //	    Runtime Error (Signaled / Nonzero Exit Status)
+//	    Wrong Answer (output mismatched, by OutputCheck)
 //	Program Runner Error
 //
+// Status is encoded as text by stable machine names (e.g. "TLE", "MLE")
+//
 // # Size
 //
 // Size defines size in bytes, underlying type is uint64 so it
-// is effective to store up to EiB of size
+// is effective to store up to EiB of size. It parses decimals with SI / IEC
+// units (e.g. "1.5GiB", "512MB") and encodes as text losslessly. Single letter
+// and IEC units are binary while SI units are decimal, notice that "512MB"
+// was parsed as 512MiB before and now is 512×10⁶ bytes
 //
 // # Limit
 //
@@ -25,10 +31,13 @@
 //
 // Result defines program running result including
 // Status, ExitStatus, Detailed Error, Time, Memory,
-// SetupTime and RunningTime (in real clock)
+// SetupTime and RunningTime (in real clock), together with the classified
+// RuntimeError (e.g. stack overflow). Result and Limit have stable JSON form
+// with durations in ns and sizes in bytes
 //
 // # Runner
 //
 // General interface to run a program, including a context
-// for cancellation
+// for cancellation. Observed decorates any runner with an Observer
+// (passed through the context) to receive the lifecycle events
 package runner
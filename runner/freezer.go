@@ -0,0 +1,30 @@
+package runner
+
+// Freezer stops and resumes all processes of the running program, it is
+// implemented by cgroup.Cgroup
+type Freezer interface {
+	Freeze() error
+	Thaw() error
+}
+
+// FreezeOnLimit freezes the program when a limit is hit, before the program is
+// killed. OnFrozen is called with the program frozen so that the resource usage
+// read is stable and the offending processes could be inspected
+type FreezeOnLimit struct {
+	Freezer  Freezer
+	OnFrozen func()
+}
+
+// Kill calls kill with the program frozen if the freezer is set. The program
+// is thawed after kill so that the pending SIGKILL is delivered (cgroup v1)
+func (f *FreezeOnLimit) Kill(kill func()) {
+	if f == nil || f.Freezer == nil {
+		kill()
+		return
+	}
+	if err := f.Freezer.Freeze(); err == nil && f.OnFrozen != nil {
+		f.OnFrozen()
+	}
+	kill()
+	f.Freezer.Thaw()
+}
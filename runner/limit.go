@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -14,3 +15,30 @@ type Limit struct {
 func (l Limit) String() string {
 	return fmt.Sprintf("Limit[Time=%v, Memory=%v]", l.TimeLimit, l.MemoryLimit)
 }
+
+// limitJSON is the wire form of Limit, time is in ns and memory is in bytes
+type limitJSON struct {
+	TimeLimit   time.Duration `json:"timeLimit"`
+	MemoryLimit uint64        `json:"memoryLimit"`
+}
+
+// MarshalJSON encodes the limit in a stable form
+func (l Limit) MarshalJSON() ([]byte, error) {
+	return json.Marshal(limitJSON{
+		TimeLimit:   l.TimeLimit,
+		MemoryLimit: uint64(l.MemoryLimit),
+	})
+}
+
+// UnmarshalJSON decodes the limit encoded by MarshalJSON
+func (l *Limit) UnmarshalJSON(b []byte) error {
+	var j limitJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*l = Limit{
+		TimeLimit:   j.TimeLimit,
+		MemoryLimit: Size(j.MemoryLimit),
+	}
+	return nil
+}
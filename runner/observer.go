@@ -0,0 +1,105 @@
+package runner
+
+import (
+	"context"
+)
+
+// Observer receives the lifecycle events of a program run, e.g. to emit
+// metrics or audit logs. Methods may be called from different goroutines
+type Observer interface {
+	// OnStart is called with the pid (in the caller pid namespace) once the
+	// program process is created
+	OnStart(pid int)
+
+	// OnExec is called once the program is executed
+	OnExec()
+
+	// OnLimitHit is called with the limit status (e.g. StatusTimeLimitExceeded)
+	// once a limit hit is detected by the runner
+	OnLimitHit(status Status)
+
+	// OnExit is called with the result after the run finished
+	OnExit(result Result)
+}
+
+// NopObserver ignores all events, it could be embedded to implement part of
+// the Observer
+type NopObserver struct{}
+
+// OnStart implements Observer
+func (NopObserver) OnStart(int) {}
+
+// OnExec implements Observer
+func (NopObserver) OnExec() {}
+
+// OnLimitHit implements Observer
+func (NopObserver) OnLimitHit(Status) {}
+
+// OnExit implements Observer
+func (NopObserver) OnExit(Result) {}
+
+// Observers notifies all of the observers in order
+type Observers []Observer
+
+// OnStart implements Observer
+func (o Observers) OnStart(pid int) {
+	for _, ob := range o {
+		ob.OnStart(pid)
+	}
+}
+
+// OnExec implements Observer
+func (o Observers) OnExec() {
+	for _, ob := range o {
+		ob.OnExec()
+	}
+}
+
+// OnLimitHit implements Observer
+func (o Observers) OnLimitHit(status Status) {
+	for _, ob := range o {
+		ob.OnLimitHit(status)
+	}
+}
+
+// OnExit implements Observer
+func (o Observers) OnExit(result Result) {
+	for _, ob := range o {
+		ob.OnExit(result)
+	}
+}
+
+type observerKey struct{}
+
+// WithObserver returns a context carrying the observer for the runners, it
+// is appended to the observer already carried by ctx
+func WithObserver(ctx context.Context, o Observer) context.Context {
+	if prev, ok := ctx.Value(observerKey{}).(Observer); ok {
+		o = Observers{prev, o}
+	}
+	return context.WithValue(ctx, observerKey{}, o)
+}
+
+// ObserverFromContext returns the observer carried by ctx, or NopObserver
+func ObserverFromContext(ctx context.Context) Observer {
+	if o, ok := ctx.Value(observerKey{}).(Observer); ok {
+		return o
+	}
+	return NopObserver{}
+}
+
+// Observed wraps a Runner (e.g. ptrace, unshare, container) to notify the
+// observer on its lifecycle events. The runner receives the observer through
+// the context, thus it should wrap other wrappers (e.g. OutputLimit) for
+// OnExit to receive the final result
+type Observed struct {
+	Runner
+	Observer Observer
+}
+
+// Run runs the underlying runner with the observer
+func (o *Observed) Run(c context.Context) Result {
+	result := o.Runner.Run(WithObserver(c, o.Observer))
+	o.Observer.OnExit(result)
+	return result
+}
@@ -0,0 +1,99 @@
+package runner
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordObserver records the events prefixed by its name
+type recordObserver struct {
+	name   string
+	mu     *sync.Mutex
+	events *[]string
+}
+
+func (o recordObserver) record(e string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	*o.events = append(*o.events, o.name+" "+e)
+}
+
+func (o recordObserver) OnStart(pid int)          { o.record("start " + strconv.Itoa(pid)) }
+func (o recordObserver) OnExec()                  { o.record("exec") }
+func (o recordObserver) OnLimitHit(status Status) { o.record("limit " + status.Name()) }
+func (o recordObserver) OnExit(result Result)     { o.record("exit " + result.Status.Name()) }
+
+func TestObserverFromContext(t *testing.T) {
+	if _, ok := ObserverFromContext(context.Background()).(NopObserver); !ok {
+		t.Error("expected NopObserver without observer")
+	}
+}
+
+func TestObserved(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	a := recordObserver{"a", &mu, &events}
+	b := recordObserver{"b", &mu, &events}
+
+	// the runner notifies the observers carried by the context
+	r := runFunc(func(c context.Context) Result {
+		o := ObserverFromContext(c)
+		o.OnStart(1)
+		o.OnExec()
+		o.OnLimitHit(StatusTimeLimitExceeded)
+		return Result{Status: StatusTimeLimitExceeded}
+	})
+	o := &Observed{Runner: r, Observer: b}
+	result := o.Run(WithObserver(context.Background(), a))
+	if result.Status != StatusTimeLimitExceeded {
+		t.Errorf("status = %v, expected %v", result.Status, StatusTimeLimitExceeded)
+	}
+
+	// events are delivered to the outer observer first, while OnExit is only
+	// delivered by Observed to its observer after the run
+	expected := []string{
+		"a start 1", "b start 1",
+		"a exec", "b exec",
+		"a limit TLE", "b limit TLE",
+		"b exit TLE",
+	}
+	if got := strings.Join(events, ","); got != strings.Join(expected, ",") {
+		t.Errorf("events = %v, expected %v", events, expected)
+	}
+}
+
+func TestObserved_Wrapper(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	a := recordObserver{"a", &mu, &events}
+
+	// OnExit receives the final result of the wrapped wrappers
+	o := &Observed{
+		Runner: &OutputCheck{
+			Runner:  runFunc(func(context.Context) Result { return Result{Status: StatusNormal} }),
+			Checker: mismatchChecker{},
+		},
+		Observer: a,
+	}
+	result := o.Run(context.Background())
+	if result.Status != StatusWrongAnswer {
+		t.Errorf("status = %v, expected %v", result.Status, StatusWrongAnswer)
+	}
+	if got := strings.Join(events, ","); !strings.HasSuffix(got, "a exit WA") {
+		t.Errorf("events = %v, expected ends with exit WA", events)
+	}
+}
+
+// mismatchChecker is an OutputChecker that never matches
+type mismatchChecker struct{}
+
+func (mismatchChecker) Mismatch() <-chan struct{} { return nil }
+func (mismatchChecker) Finish() bool              { return false }
+func (mismatchChecker) Result() (bool, int64)     { return false, 0 }
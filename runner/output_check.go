@@ -0,0 +1,76 @@
+package runner
+
+import (
+	"context"
+)
+
+// OutputChecker compares the program output with the expected output as it
+// is written, it is implemented by pipe.Checker
+type OutputChecker interface {
+	// Mismatch is closed on the first mismatch
+	Mismatch() <-chan struct{}
+	// Finish checks the end of output after the output is fully written
+	Finish() bool
+	// Result returns whether matched and the byte offset of the first mismatch
+	Result() (bool, int64)
+}
+
+// OutputCheck wraps a Runner to kill the program on the first mismatch of the
+// output checked by Checker, and reports StatusWrongAnswer together with the
+// mismatch offset
+type OutputCheck struct {
+	Runner
+	Checker OutputChecker
+
+	// Done is closed when the output is fully written to the checker (e.g.
+	// pipe.CheckerBuffer.Done), the end of output is checked after it is
+	// closed, thus the write end must not be held open by the caller after Run
+	Done <-chan struct{}
+}
+
+// Run cancels the underlying runner once mismatch and overrides the status
+func (o *OutputCheck) Run(c context.Context) Result {
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	var killed bool
+	watchDone := make(chan struct{})
+	go func() {
+		defer close(watchDone)
+		select {
+		case <-o.Checker.Mismatch():
+			killed = true
+			ObserverFromContext(c).OnLimitHit(StatusWrongAnswer)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	result := o.Runner.Run(ctx)
+	cancel()
+	<-watchDone
+
+	// the rest of the output might be checked after the program exited
+	if o.Done != nil {
+		select {
+		case <-o.Done:
+			o.Checker.Finish()
+		case <-c.Done():
+		}
+	}
+	ok, offset := o.Checker.Result()
+	if ok {
+		return result
+	}
+	// runtime error of the program takes precedence unless killed by mismatch
+	switch result.Status {
+	case StatusNonzeroExitStatus, StatusSignalled, StatusTimeLimitExceeded:
+		if !killed {
+			return result
+		}
+		fallthrough
+	case StatusNormal:
+		result.Status = StatusWrongAnswer
+		result.MismatchOffset = offset
+	}
+	return result
+}
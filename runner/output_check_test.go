@@ -0,0 +1,75 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tobiichi3227/go-sandbox/pkg/pipe"
+)
+
+// shellRunner runs the shell script with stdout redirected to w
+func shellRunner(script string, w *pipe.CheckerBuffer) Runner {
+	return runFunc(func(c context.Context) Result {
+		cmd := exec.CommandContext(c, "/bin/sh", "-c", script)
+		cmd.Stdout = w.W
+		err := cmd.Run()
+		w.W.Close()
+
+		var exitErr *exec.ExitError
+		switch {
+		case err == nil:
+			return Result{Status: StatusNormal}
+		case errors.As(err, &exitErr) && exitErr.ExitCode() < 0:
+			return Result{Status: StatusSignalled}
+		case errors.As(err, &exitErr):
+			return Result{Status: StatusNonzeroExitStatus, ExitStatus: exitErr.ExitCode()}
+		default:
+			return Result{Status: StatusRunnerError, Error: err.Error()}
+		}
+	})
+}
+
+func TestOutputCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		status   Status
+		offset   int64
+		duration time.Duration
+	}{
+		{"Accepted", "echo 1 2; echo 3", StatusNormal, 0, 0},
+		{"Mismatch", "echo 1 2; echo 4; sleep 10", StatusWrongAnswer, 4, 5 * time.Second},
+		{"MissingOutput", "echo 1 2", StatusWrongAnswer, 4, 0},
+		{"ExtraOutput", "echo 1 2 3 4", StatusWrongAnswer, 6, 0},
+		{"RuntimeError", "echo 1 2; exit 3", StatusNonzeroExitStatus, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := pipe.NewChecker(strings.NewReader("1 2\n3\n"), pipe.CheckToken, 0)
+			buf, err := pipe.NewCheckerBuffer(checker)
+			if err != nil {
+				t.Fatal(err)
+			}
+			o := &OutputCheck{
+				Runner:  shellRunner(tt.script, buf),
+				Checker: checker,
+				Done:    buf.Done,
+			}
+			start := time.Now()
+			result := o.Run(context.Background())
+			if result.Status != tt.status {
+				t.Fatalf("status = %v, expected %v", result, tt.status)
+			}
+			if result.MismatchOffset != tt.offset {
+				t.Errorf("offset = %d, expected %d", result.MismatchOffset, tt.offset)
+			}
+			if d := time.Since(start); tt.duration > 0 && d >= tt.duration {
+				t.Errorf("program is not killed on mismatch: %v", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package runner
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// OutputLimit wraps a Runner to kill the program when any of the captured
+// outputs overflows (e.g. pipe.Buffer.Overflow) since RLIMIT_FSIZE does not
+// apply to pipes, and reports StatusOutputLimitExceeded
+type OutputLimit struct {
+	Runner
+	Overflow []<-chan struct{}
+
+	// Done are closed when the outputs are fully copied (e.g. pipe.Buffer.Done)
+	// in the same order of Overflow. The output written just before exit is
+	// counted by waiting for them after the program exited, thus the write
+	// ends must not be held open by the caller after Run
+	Done []<-chan struct{}
+}
+
+// Run cancels the underlying runner once overflow and overrides the status
+func (o *OutputLimit) Run(c context.Context) Result {
+	ctx, cancel := context.WithCancel(c)
+	defer cancel()
+
+	var exceeded atomic.Bool
+	limitHit := func() {
+		if !exceeded.Swap(true) {
+			ObserverFromContext(c).OnLimitHit(StatusOutputLimitExceeded)
+		}
+	}
+	done := make(chan struct{}, len(o.Overflow))
+	for _, ch := range o.Overflow {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			select {
+			case <-ch:
+				limitHit()
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	result := o.Runner.Run(ctx)
+
+	// ensure the observer is not notified after the runner returned
+	cancel()
+	for range o.Overflow {
+		<-done
+	}
+
+	// the overflow might be noticed after the program exited
+	for i, ch := range o.Overflow {
+		if i < len(o.Done) {
+			select {
+			case <-ch:
+			case <-o.Done[i]:
+			case <-c.Done():
+			}
+		}
+		select {
+		case <-ch:
+			limitHit()
+		default:
+		}
+	}
+	if exceeded.Load() {
+		switch result.Status {
+		case StatusNormal, StatusNonzeroExitStatus, StatusSignalled, StatusTimeLimitExceeded:
+			result.Status = StatusOutputLimitExceeded
+		}
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/tobiichi3227/go-sandbox/pkg/pipe"
+)
+
+// runFunc is a Runner stub
+type runFunc func(context.Context) Result
+
+func (f runFunc) Run(c context.Context) Result {
+	return f(c)
+}
+
+func TestOutputLimit(t *testing.T) {
+	const max = 1 << 10
+	tests := []struct {
+		name     string
+		size     int
+		expected Status
+		events   []string
+	}{
+		{"Within", max, StatusNormal, []string{"o exit OK"}},
+		{"Exceeded", max + 1, StatusOutputLimitExceeded, []string{"o limit OLE", "o exit OLE"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := pipe.NewBuffer(max)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// writes and exits before the pipe is copied
+			r := runFunc(func(context.Context) Result {
+				buf.W.Write(bytes.Repeat([]byte{'a'}, tt.size))
+				buf.W.Close()
+				return Result{Status: StatusNormal}
+			})
+			var (
+				mu     sync.Mutex
+				events []string
+			)
+			o := &Observed{
+				Runner: &OutputLimit{
+					Runner:   r,
+					Overflow: []<-chan struct{}{buf.Overflow},
+					Done:     []<-chan struct{}{buf.Done},
+				},
+				Observer: recordObserver{"o", &mu, &events},
+			}
+			if result := o.Run(context.Background()); result.Status != tt.expected {
+				t.Errorf("status = %v, expected %v", result.Status, tt.expected)
+			}
+			if !slices.Equal(events, tt.events) {
+				t.Errorf("events = %v, expected %v", events, tt.events)
+			}
+		})
+	}
+}
+
+func TestOutputLimit_Cancel(t *testing.T) {
+	buf, err := pipe.NewBuffer(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer buf.W.Close()
+
+	// the program keeps running until canceled by the overflow
+	r := runFunc(func(c context.Context) Result {
+		buf.W.Write(bytes.Repeat([]byte{'a'}, 16))
+		<-c.Done()
+		return Result{Status: StatusSignalled}
+	})
+	o := &OutputLimit{
+		Runner:   r,
+		Overflow: []<-chan struct{}{buf.Overflow},
+	}
+	if result := o.Run(context.Background()); result.Status != StatusOutputLimitExceeded {
+		t.Errorf("status = %v, expected %v", result.Status, StatusOutputLimitExceeded)
+	}
+}
@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	// clockTicks is the USER_HZ used by /proc/<pid>/stat
+	clockTicks = 100
+
+	// stackGuardGap is the default stack_guard_gap in pages
+	stackGuardGap = 256
+)
+
+// ProcCPUUsage reads the CPU time (user and system) of pid and its waited
+// children from /proc/<pid>/stat, in the precision of clock ticks (10ms)
+func ProcCPUUsage(pid int) (time.Duration, error) {
+	b, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return 0, err
+	}
+	ticks, err := parseStatCPUTicks(b)
+	if err != nil {
+		return 0, fmt.Errorf("runner: invalid stat for pid %d: %w", pid, err)
+	}
+	return time.Duration(ticks) * time.Second / clockTicks, nil
+}
+
+// parseStatCPUTicks sums utime, stime, cutime and cstime in /proc/<pid>/stat
+func parseStatCPUTicks(b []byte) (uint64, error) {
+	// comm (field 2) may contain space and parentheses
+	i := bytes.LastIndexByte(b, ')')
+	if i < 0 {
+		return 0, fmt.Errorf("no comm")
+	}
+	// fields after comm start from state (field 3)
+	f := bytes.Fields(b[i+1:])
+	if len(f) < 15 {
+		return 0, fmt.Errorf("too few fields")
+	}
+	var ticks uint64
+	for _, s := range f[11:15] { // utime (14), stime (15), cutime (16), cstime (17)
+		v, err := strconv.ParseUint(string(s), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		ticks += v
+	}
+	return ticks, nil
+}
+
+// IsStackFault reports whether the fault address addr of pid is within the
+// stack guard gap below its main thread stack, i.e. the stack overflowed
+func IsStackFault(pid int, addr uintptr) (bool, error) {
+	b, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/maps")
+	if err != nil {
+		return false, err
+	}
+	for _, l := range bytes.Split(b, []byte{'\n'}) {
+		if !bytes.HasSuffix(l, []byte("[stack]")) {
+			continue
+		}
+		r, _, _ := bytes.Cut(l, []byte{' '})
+		s, _, _ := bytes.Cut(r, []byte{'-'})
+		start, err := strconv.ParseUint(string(s), 16, 64)
+		if err != nil {
+			return false, fmt.Errorf("runner: invalid maps for pid %d: %w", pid, err)
+		}
+		gap := uint64(stackGuardGap * os.Getpagesize())
+		return uint64(addr) < start && uint64(addr)+gap >= start, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,60 @@
+package runner
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseStatCPUTicks(t *testing.T) {
+	tests := []struct {
+		name  string
+		stat  string
+		ticks uint64
+		err   bool
+	}{
+		{"Normal", "42 (a.out) R 1 42 42 0 -1 4194304 100 0 0 0 10 20 30 40 20 0 1 0", 100, false},
+		{"Comm with parentheses", "42 (a) b (c)) R 1 42 42 0 -1 4194304 100 0 0 0 1 2 3 4 20 0 1 0", 10, false},
+		{"No comm", "42 a.out R 1", 0, true},
+		{"Too few fields", "42 (a.out) R 1 42 42 0 -1 4194304 100 0 0 0 10 20 30", 0, true},
+		{"Invalid number", "42 (a.out) R 1 42 42 0 -1 4194304 100 0 0 0 10 x 30 40 20", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticks, err := parseStatCPUTicks([]byte(tt.stat))
+			if tt.err {
+				if err == nil {
+					t.Errorf("parseStatCPUTicks(%q) = %d; expected error", tt.stat, ticks)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ticks != tt.ticks {
+				t.Errorf("parseStatCPUTicks(%q) = %d; expected %d", tt.stat, ticks, tt.ticks)
+			}
+		})
+	}
+}
+
+func TestProcCPUUsage(t *testing.T) {
+	before, err := ProcCPUUsage(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// burn CPU for more than a clock tick
+	for start := time.Now(); time.Since(start) < 50*time.Millisecond; {
+	}
+	after, err := ProcCPUUsage(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after <= before {
+		t.Errorf("usage %v is not increased from %v", after, before)
+	}
+
+	if _, err := ProcCPUUsage(-1); err == nil {
+		t.Error("expected error for invalid pid")
+	}
+}
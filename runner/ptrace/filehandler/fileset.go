@@ -6,9 +6,17 @@ import (
 )
 
 // FileSet stores the file permissions in the hierarchical set
+//
+// Entries are exact names, directories ending with "/" (including all its
+// descendants), "dir/*" (first level only), glob / regular expression
+// patterns (see pattern) or deny entries prefixed by "!". Deny entries
+// override all other entries regardless of the order added.
 type FileSet struct {
 	Set        map[string]bool
 	SystemRoot bool
+
+	patterns []pattern
+	deny     *FileSet
 }
 
 // FilePerm stores the permission apply to the file
@@ -23,11 +31,47 @@ const (
 
 // NewFileSet creates the new file set
 func NewFileSet() FileSet {
-	return FileSet{make(map[string]bool), false}
+	return FileSet{Set: make(map[string]bool)}
 }
 
-// IsInSetSmart same from uoj-judger
+// IsInSetSmart same from uoj-judger, with patterns and deny entries
 func (s *FileSet) IsInSetSmart(name string) bool {
+	return !s.IsDenied(name) && s.isAllowed(name)
+}
+
+// IsDenied determines whether the path matches the deny entries. Invalid deny
+// pattern denies everything
+func (s *FileSet) IsDenied(name string) bool {
+	if s.deny == nil {
+		return false
+	}
+	for _, p := range s.deny.patterns {
+		if p.invalid {
+			return true
+		}
+	}
+	return s.deny.isAllowed(name)
+}
+
+// match checks the path and its resolved path, it is denied if either of them
+// is denied
+func (s *FileSet) match(name, real string) bool {
+	if s.denied(name, real) {
+		return false
+	}
+	return s.isAllowed(name) || (real != "" && s.isAllowed(real))
+}
+
+func (s *FileSet) denied(name, real string) bool {
+	return s.IsDenied(name) || (real != "" && s.IsDenied(real))
+}
+
+func (s *FileSet) isAllowed(name string) bool {
+	for _, p := range s.patterns {
+		if p.match(name) {
+			return true
+		}
+	}
 	if s.Set[name] {
 		return true
 	}
@@ -56,6 +100,18 @@ func (s *FileSet) IsInSetSmart(name string) bool {
 
 // Add adds a single file path into the FileSet
 func (s *FileSet) Add(name string) {
+	if after, ok := strings.CutPrefix(name, DenyPrefix); ok {
+		if s.deny == nil {
+			d := NewFileSet()
+			s.deny = &d
+		}
+		s.deny.Add(after)
+		return
+	}
+	if isPattern(name) {
+		s.patterns = append(s.patterns, newPattern(name))
+		return
+	}
 	if name == "/" {
 		s.SystemRoot = true
 	} else {
@@ -67,14 +123,17 @@ func (s *FileSet) Add(name string) {
 // If path is relative path, add according to the workPath
 func (s *FileSet) AddRange(names []string, workPath string) {
 	for _, n := range names {
-		if filepath.IsAbs(n) {
-			if n == "/" {
-				s.SystemRoot = true
-			} else {
-				s.Set[n] = true
-			}
-		} else {
-			s.Set[filepath.Join(workPath, n)+"/"] = true
+		prefix := ""
+		if after, ok := strings.CutPrefix(n, DenyPrefix); ok {
+			prefix, n = DenyPrefix, after
+		}
+		switch {
+		case filepath.IsAbs(n), strings.HasPrefix(n, RegexPrefix):
+			s.Add(prefix + n)
+		case isPattern(n):
+			s.Add(prefix + filepath.Join(workPath, n))
+		default:
+			s.Add(prefix + filepath.Join(workPath, n) + "/")
 		}
 	}
 }
@@ -91,22 +150,50 @@ func NewFileSets() *FileSets {
 
 // IsWritableFile determines whether the file path inside the write set
 func (s *FileSets) IsWritableFile(name string) bool {
-	return s.Writable.IsInSetSmart(name) || s.Writable.IsInSetSmart(realPath(name))
+	return s.Writable.match(name, realPath(name))
 }
 
 // IsReadableFile determines whether the file path inside the read / write set
+// and not denied by read set
 func (s *FileSets) IsReadableFile(name string) bool {
-	return s.IsWritableFile(name) || s.Readable.IsInSetSmart(name) || s.Readable.IsInSetSmart(realPath(name))
+	real := realPath(name)
+	return s.Readable.match(name, real) ||
+		(s.IsWritableFile(name) && !s.Readable.denied(name, real))
 }
 
 // IsStatableFile determines whether the file path inside the stat / read / write set
+// and not denied by stat set
 func (s *FileSets) IsStatableFile(name string) bool {
-	return s.IsReadableFile(name) || s.Statable.IsInSetSmart(name) || s.Statable.IsInSetSmart(realPath(name))
+	real := realPath(name)
+	return s.Statable.match(name, real) ||
+		((s.IsReadableFile(name) || s.IsWritableFile(name)) && !s.Statable.denied(name, real))
 }
 
 // IsSoftBanFile determines whether the file path inside the softban set
 func (s *FileSets) IsSoftBanFile(name string) bool {
-	return s.SoftBan.IsInSetSmart(name) || s.SoftBan.IsInSetSmart(realPath(name))
+	return s.SoftBan.match(name, realPath(name))
+}
+
+// IsWritableLink determines whether the file path inside the write set
+// without following the final symbolic link
+func (s *FileSets) IsWritableLink(name string) bool {
+	return s.Writable.match(name, linkPath(name))
+}
+
+// IsReadableLink determines whether the file path inside the read / write set
+// without following the final symbolic link
+func (s *FileSets) IsReadableLink(name string) bool {
+	link := linkPath(name)
+	return s.Readable.match(name, link) ||
+		(s.IsWritableLink(name) && !s.Readable.denied(name, link))
+}
+
+// IsStatableLink determines whether the file path inside the stat / read / write set
+// without following the final symbolic link
+func (s *FileSets) IsStatableLink(name string) bool {
+	link := linkPath(name)
+	return s.Statable.match(name, link) ||
+		((s.IsReadableLink(name) || s.IsWritableLink(name)) && !s.Statable.denied(name, link))
 }
 
 // AddFilePermission adds the file into fileSets according to the given permission
@@ -124,11 +211,20 @@ func (s *FileSets) AddFilePermission(name string, mode FilePerm) {
 }
 
 // GetExtraSet evaluates the concatenated file set according to real path or raw path
+// patterns are kept as is
 func GetExtraSet(extra, raw []string) []string {
 	rt := make([]string, 0, len(extra)+len(raw))
 	rt = append(rt, raw...)
 	for _, v := range extra {
-		rt = append(rt, realPath(v))
+		prefix, p := "", v
+		if after, ok := strings.CutPrefix(v, DenyPrefix); ok {
+			prefix, p = DenyPrefix, after
+		}
+		// unresolved deny entry keeps the raw path since empty path refers to the work path
+		if r := realPath(p); !isPattern(p) && (r != "" || prefix == "") {
+			p = r
+		}
+		rt = append(rt, prefix+p)
 	}
 	return rt
 }
@@ -148,3 +244,13 @@ func realPath(p string) string {
 	}
 	return f
 }
+
+// linkPath evaluates the symbolic links in the parent directory but keeps the
+// final path element as is
+func linkPath(p string) string {
+	d := realPath(filepath.Dir(p))
+	if d == "" {
+		return ""
+	}
+	return filepath.Join(d, filepath.Base(p))
+}
@@ -2,6 +2,8 @@ package filehandler
 
 import (
 	"maps"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -153,3 +155,32 @@ func TestFileSet_AddRange(t *testing.T) {
 		})
 	}
 }
+
+// Unit test for IsXxxLink methods
+func TestFileSets_IsLink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	link := filepath.Join(dir, "link")
+	if err := os.WriteFile(target, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := NewFileSets()
+	fs.Readable.Add(target)
+
+	if !fs.IsReadableFile(link) {
+		t.Errorf("IsReadableFile(%q) = false; expected true", link)
+	}
+	if fs.IsReadableLink(link) {
+		t.Errorf("IsReadableLink(%q) = true; expected false", link)
+	}
+	if !fs.IsReadableLink(target) {
+		t.Errorf("IsReadableLink(%q) = false; expected true", target)
+	}
+	if !fs.IsStatableLink(target) || fs.IsWritableLink(target) {
+		t.Errorf("unexpected permission for %q", target)
+	}
+}
@@ -35,6 +35,30 @@ func (h *Handler) CheckStat(fn string) ptracer.TraceAction {
 	return ptracer.TraceAllow
 }
 
+// CheckReadNoFollow checks whether the symbolic link itself have read permission
+func (h *Handler) CheckReadNoFollow(fn string) ptracer.TraceAction {
+	if !h.FileSet.IsReadableLink(fn) {
+		return h.onDgsFileDetect(fn)
+	}
+	return ptracer.TraceAllow
+}
+
+// CheckWriteNoFollow checks whether the symbolic link itself have write permission
+func (h *Handler) CheckWriteNoFollow(fn string) ptracer.TraceAction {
+	if !h.FileSet.IsWritableLink(fn) {
+		return h.onDgsFileDetect(fn)
+	}
+	return ptracer.TraceAllow
+}
+
+// CheckStatNoFollow checks whether the symbolic link itself have stat permission
+func (h *Handler) CheckStatNoFollow(fn string) ptracer.TraceAction {
+	if !h.FileSet.IsStatableLink(fn) {
+		return h.onDgsFileDetect(fn)
+	}
+	return ptracer.TraceAllow
+}
+
 // CheckSyscall checks syscalls other than allowed and traced against the
 // SyscallCounter
 func (h *Handler) CheckSyscall(syscallName string) ptracer.TraceAction {
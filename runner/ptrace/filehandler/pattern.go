@@ -0,0 +1,105 @@
+package filehandler
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// RegexPrefix is the prefix of FileSet entry for regular expression pattern
+const RegexPrefix = "re:"
+
+// DenyPrefix is the prefix of FileSet entry to deny the matched paths
+const DenyPrefix = "!"
+
+// pattern matches path by glob or regular expression
+//
+// Glob patterns follow path.Match for each path element, and "**" matches
+// zero or more path elements. Glob pattern ending with "/" matches the path
+// and all its descendants. Regular expressions match the full path.
+type pattern struct {
+	glob    []string
+	dir     bool
+	re      *regexp.Regexp
+	invalid bool
+}
+
+// isPattern determines whether the FileSet entry should be matched as pattern
+// rather than exact name. The "dir/*" entry is matched by the set for
+// compatibility
+func isPattern(name string) bool {
+	return strings.HasPrefix(name, RegexPrefix) ||
+		strings.ContainsAny(strings.TrimSuffix(name, "/*"), "*?[")
+}
+
+// newPattern parses the pattern, invalid pattern never matches
+func newPattern(p string) pattern {
+	if after, ok := strings.CutPrefix(p, RegexPrefix); ok {
+		re, err := regexp.Compile("^(?:" + after + ")$")
+		return pattern{re: re, invalid: err != nil}
+	}
+	dir := strings.HasSuffix(p, "/")
+	glob := strings.Split(strings.TrimSuffix(p, "/"), "/")
+	for _, g := range glob {
+		if _, err := path.Match(g, ""); err != nil {
+			return pattern{invalid: true}
+		}
+	}
+	return pattern{glob: glob, dir: dir}
+}
+
+// ValidatePattern checks whether the FileSet entry is a valid pattern
+func ValidatePattern(name string) error {
+	name = strings.TrimPrefix(name, DenyPrefix)
+	if after, ok := strings.CutPrefix(name, RegexPrefix); ok {
+		_, err := regexp.Compile(after)
+		return err
+	}
+	for _, g := range strings.Split(name, "/") {
+		if _, err := path.Match(g, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *pattern) match(name string) bool {
+	if p.invalid {
+		return false
+	}
+	if p.re != nil {
+		return p.re.MatchString(name)
+	}
+	elem := strings.Split(name, "/")
+	if !p.dir {
+		return matchGlob(p.glob, elem)
+	}
+	for i := len(elem); i > 0; i-- {
+		if matchGlob(p.glob, elem[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches path elements against glob elements
+func matchGlob(glob, elem []string) bool {
+	for len(glob) > 0 {
+		if glob[0] == "**" {
+			for i := 0; i <= len(elem); i++ {
+				if matchGlob(glob[1:], elem[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elem) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(glob[0], elem[0]); !ok {
+			return false
+		}
+		glob, elem = glob[1:], elem[1:]
+	}
+	return len(elem) == 0
+}
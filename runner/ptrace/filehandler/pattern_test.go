@@ -0,0 +1,117 @@
+package filehandler
+
+import "testing"
+
+func TestFileSet_Pattern(t *testing.T) {
+	fs := NewFileSet()
+	fs.Add("/usr/lib/**/*.so")
+	fs.Add("/tmp/*.class")
+	fs.Add("/usr/lib/python3*/")
+	fs.Add("re:/proc/[0-9]+/(stat|status)")
+
+	tests := []struct {
+		name     string
+		input    string
+		expected bool
+	}{
+		{"Double star zero level", "/usr/lib/libc.so", true},
+		{"Double star multi level", "/usr/lib/x86_64-linux-gnu/a/libm.so", true},
+		{"Double star suffix mismatch", "/usr/lib/x86_64-linux-gnu/libm.so.6", false},
+		{"Single star", "/tmp/Main.class", true},
+		{"Single star does not cross /", "/tmp/a/Main.class", false},
+		{"Directory pattern", "/usr/lib/python3.12", true},
+		{"Directory pattern descendant", "/usr/lib/python3.12/os.py", true},
+		{"Directory pattern mismatch", "/usr/lib/python2.7/os.py", false},
+		{"Regex", "/proc/12/status", true},
+		{"Regex full match", "/proc/12/statm", false},
+		{"Regex mismatch", "/proc/self/stat", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fs.IsInSetSmart(tt.input); got != tt.expected {
+				t.Errorf("IsInSetSmart(%q) = %v; expected %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFileSet_Deny(t *testing.T) {
+	fs := NewFileSet()
+	fs.Add("!/etc/shadow")
+	fs.Add("/etc/")
+	fs.Add("/home/")
+	fs.Add("!/etc/ssl/")
+	fs.Add("!re:.*\\.key")
+
+	tests := []struct {
+		name     string
+		input    string
+		expected bool
+	}{
+		{"Allowed by directory", "/etc/passwd", true},
+		{"Deny overrides exact allow", "/etc/shadow", false},
+		{"Deny added after allow", "/etc/ssl/certs/ca.pem", false},
+		{"Deny pattern overrides directory", "/home/a.key", false},
+		{"Allowed by directory outside deny", "/home/a.txt", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fs.IsInSetSmart(tt.input); got != tt.expected {
+				t.Errorf("IsInSetSmart(%q) = %v; expected %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFileSet_InvalidPattern(t *testing.T) {
+	fs := NewFileSet()
+	fs.Add("/tmp/[a-")
+	if fs.IsInSetSmart("/tmp/[a-") {
+		t.Errorf("invalid allow pattern should not match")
+	}
+	fs.Add("/tmp/")
+	fs.Add("!re:(")
+	if fs.IsInSetSmart("/tmp/a") {
+		t.Errorf("invalid deny pattern should deny everything")
+	}
+	if ValidatePattern("!re:(") == nil || ValidatePattern("/tmp/[a-") == nil {
+		t.Errorf("ValidatePattern should report invalid pattern")
+	}
+	if err := ValidatePattern("/usr/lib/**/*.so"); err != nil {
+		t.Errorf("ValidatePattern: %v", err)
+	}
+}
+
+func TestFileSets_DenyPrecedence(t *testing.T) {
+	fs := NewFileSets()
+	fs.Writable.AddRange([]string{"/w/"}, "/w")
+	fs.Readable.AddRange([]string{"!/w/secret", "!*.log"}, "/w")
+	fs.Statable.AddRange([]string{"!/w/hidden"}, "/w")
+
+	tests := []struct {
+		name                        string
+		input                       string
+		write, read, stat, readLink bool
+	}{
+		{"Inherit from write", "/w/a", true, true, true, true},
+		{"Read denied", "/w/secret", true, false, true, false},
+		{"Read denied by relative pattern", "/w/out.log", true, false, true, false},
+		{"Stat denied", "/w/hidden", true, true, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fs.IsWritableFile(tt.input); got != tt.write {
+				t.Errorf("IsWritableFile(%q) = %v; expected %v", tt.input, got, tt.write)
+			}
+			if got := fs.IsReadableFile(tt.input); got != tt.read {
+				t.Errorf("IsReadableFile(%q) = %v; expected %v", tt.input, got, tt.read)
+			}
+			if got := fs.IsStatableFile(tt.input); got != tt.stat {
+				t.Errorf("IsStatableFile(%q) = %v; expected %v", tt.input, got, tt.stat)
+			}
+			if got := fs.IsReadableLink(tt.input); got != tt.readLink {
+				t.Errorf("IsReadableLink(%q) = %v; expected %v", tt.input, got, tt.readLink)
+			}
+		})
+	}
+}
@@ -8,8 +8,12 @@ import (
 
 	"github.com/tobiichi3227/go-sandbox/pkg/seccomp/libseccomp"
 	"github.com/tobiichi3227/go-sandbox/ptracer"
+	"golang.org/x/sys/unix"
 )
 
+// atFdCwd is the dirfd argument for syscalls resolved relative to cwd
+const atFdCwd = uint(unix.AT_FDCWD & 0xffffffff)
+
 type tracerHandler struct {
 	ShowDetails, Unsafe bool
 	Handler             Handler
@@ -21,40 +25,100 @@ func (h *tracerHandler) Debug(v ...interface{}) {
 	}
 }
 
-func (h *tracerHandler) getString(ctx *ptracer.Context, addr uint) string {
-	return absPath(ctx.Pid, ctx.GetString(uintptr(addr)))
+// getPath reads the path argument and resolves it relative to dirfd
+func (h *tracerHandler) getPath(ctx *ptracer.Context, dirfd, addr uint) (string, error) {
+	return resolvePath(ctx.Pid, int(int32(dirfd)), ctx.GetString(uintptr(addr)))
 }
 
-func (h *tracerHandler) checkOpen(ctx *ptracer.Context, addr uint, flags uint) ptracer.TraceAction {
-	fn := h.getString(ctx, addr)
+func (h *tracerHandler) checkOpen(ctx *ptracer.Context, dirfd, addr, flags uint) ptracer.TraceAction {
+	fn, err := h.getPath(ctx, dirfd, addr)
+	if err != nil {
+		h.Debug("open: failed to resolve path: ", err)
+		return ptracer.TraceKill
+	}
 	isReadOnly := (flags&syscall.O_ACCMODE == syscall.O_RDONLY) &&
 		(flags&syscall.O_CREAT == 0) &&
 		(flags&syscall.O_EXCL == 0) &&
 		(flags&syscall.O_TRUNC == 0)
+	follow := flags&syscall.O_NOFOLLOW == 0
 
 	h.Debug("open: ", fn, getFileMode(flags))
 	if isReadOnly {
-		return h.Handler.CheckRead(fn)
+		return h.checkFile(fn, follow, h.Handler.CheckRead, NoFollowHandler.CheckReadNoFollow)
 	}
-	return h.Handler.CheckWrite(fn)
+	// the file opened for read and write (or created for read) must be both
+	// readable and writable, so that the read deny entries cannot be bypassed
+	if flags&syscall.O_ACCMODE != syscall.O_WRONLY {
+		return maxAction(
+			h.checkFile(fn, follow, h.Handler.CheckRead, NoFollowHandler.CheckReadNoFollow),
+			h.checkFile(fn, follow, h.Handler.CheckWrite, NoFollowHandler.CheckWriteNoFollow))
+	}
+	return h.checkFile(fn, follow, h.Handler.CheckWrite, NoFollowHandler.CheckWriteNoFollow)
 }
 
-func (h *tracerHandler) checkRead(ctx *ptracer.Context, addr uint) ptracer.TraceAction {
-	fn := h.getString(ctx, addr)
+func (h *tracerHandler) checkRead(ctx *ptracer.Context, dirfd, addr, flags uint) ptracer.TraceAction {
+	fn, err := h.getPath(ctx, dirfd, addr)
+	if err != nil {
+		h.Debug("check read: failed to resolve path: ", err)
+		return ptracer.TraceKill
+	}
 	h.Debug("check read: ", fn)
-	return h.Handler.CheckRead(fn)
+	return h.checkFile(fn, flags&unix.AT_SYMLINK_NOFOLLOW == 0, h.Handler.CheckRead, NoFollowHandler.CheckReadNoFollow)
 }
 
-func (h *tracerHandler) checkWrite(ctx *ptracer.Context, addr uint) ptracer.TraceAction {
-	fn := h.getString(ctx, addr)
+func (h *tracerHandler) checkWrite(ctx *ptracer.Context, dirfd, addr, flags uint) ptracer.TraceAction {
+	fn, err := h.getPath(ctx, dirfd, addr)
+	if err != nil {
+		h.Debug("check write: failed to resolve path: ", err)
+		return ptracer.TraceKill
+	}
 	h.Debug("check write: ", fn)
-	return h.Handler.CheckWrite(fn)
+	return h.checkFile(fn, flags&unix.AT_SYMLINK_NOFOLLOW == 0, h.Handler.CheckWrite, NoFollowHandler.CheckWriteNoFollow)
 }
 
-func (h *tracerHandler) checkStat(ctx *ptracer.Context, addr uint) ptracer.TraceAction {
-	fn := h.getString(ctx, addr)
+func (h *tracerHandler) checkStat(ctx *ptracer.Context, dirfd, addr, flags uint) ptracer.TraceAction {
+	// fstat is implemented as fstatat(fd, "", AT_EMPTY_PATH) and the file
+	// referred by the fd have been checked when it was opened
+	if flags&unix.AT_EMPTY_PATH != 0 && int(int32(dirfd)) != unix.AT_FDCWD && ctx.GetString(uintptr(addr)) == "" {
+		h.Debug("check stat: fd ", int(int32(dirfd)))
+		return ptracer.TraceAllow
+	}
+	fn, err := h.getPath(ctx, dirfd, addr)
+	if err != nil {
+		h.Debug("check stat: failed to resolve path: ", err)
+		return ptracer.TraceKill
+	}
 	h.Debug("check stat: ", fn)
-	return h.Handler.CheckStat(fn)
+	return h.checkFile(fn, flags&unix.AT_SYMLINK_NOFOLLOW == 0, h.Handler.CheckStat, NoFollowHandler.CheckStatNoFollow)
+}
+
+// checkSymlink checks both the link path and the link target for write
+// permission since the link grants the access of the link path to its target
+func (h *tracerHandler) checkSymlink(ctx *ptracer.Context, target, dirfd, addr uint) ptracer.TraceAction {
+	fn, err := h.getPath(ctx, dirfd, addr)
+	if err != nil {
+		h.Debug("symlink: failed to resolve path: ", err)
+		return ptracer.TraceKill
+	}
+	t := ctx.GetString(uintptr(target))
+	if !filepath.IsAbs(t) {
+		t = filepath.Join(filepath.Dir(fn), t)
+	}
+	h.Debug("symlink: ", fn, " -> ", t)
+	return maxAction(
+		h.checkFile(fn, false, h.Handler.CheckWrite, NoFollowHandler.CheckWriteNoFollow),
+		h.Handler.CheckWrite(filepath.Clean(t)))
+}
+
+// checkFile checks the file by NoFollowHandler if the syscall does not follow
+// the final symbolic link and the handler supports it
+func (h *tracerHandler) checkFile(fn string, follow bool, check func(string) ptracer.TraceAction,
+	checkNoFollow func(NoFollowHandler, string) ptracer.TraceAction,
+) ptracer.TraceAction {
+	if nh, ok := h.Handler.(NoFollowHandler); ok && !follow {
+		return checkNoFollow(nh, fn)
+	}
+	return check(fn)
 }
 
 func (h *tracerHandler) Handle(ctx *ptracer.Context) ptracer.TraceAction {
@@ -66,45 +130,132 @@ func (h *tracerHandler) Handle(ctx *ptracer.Context) ptracer.TraceAction {
 		return ptracer.TraceKill
 	}
 
-	// not checking dirfd for now (maybe unsafe)
 	action := ptracer.TraceKill
 	switch syscallName {
 	case "open":
-		action = h.checkOpen(ctx, ctx.Arg0(), ctx.Arg1())
-	case "openat", "openat2":
-		action = h.checkOpen(ctx, ctx.Arg1(), ctx.Arg2())
+		action = h.checkOpen(ctx, atFdCwd, ctx.Arg0(), ctx.Arg1())
+	case "openat":
+		action = h.checkOpen(ctx, ctx.Arg0(), ctx.Arg1(), ctx.Arg2())
+	case "openat2":
+		// flags are stored in struct open_how, assume the worst
+		action = h.checkOpen(ctx, ctx.Arg0(), ctx.Arg1(), syscall.O_RDWR)
 
 	case "readlink":
-		action = h.checkRead(ctx, ctx.Arg0())
+		action = h.checkRead(ctx, atFdCwd, ctx.Arg0(), unix.AT_SYMLINK_NOFOLLOW)
 	case "readlinkat":
-		action = h.checkRead(ctx, ctx.Arg1())
+		action = h.checkRead(ctx, ctx.Arg0(), ctx.Arg1(), unix.AT_SYMLINK_NOFOLLOW)
 
 	case "unlink":
-		action = h.checkWrite(ctx, ctx.Arg0())
+		action = h.checkWrite(ctx, atFdCwd, ctx.Arg0(), unix.AT_SYMLINK_NOFOLLOW)
 	case "unlinkat":
-		action = h.checkWrite(ctx, ctx.Arg1())
+		action = h.checkWrite(ctx, ctx.Arg0(), ctx.Arg1(), unix.AT_SYMLINK_NOFOLLOW)
 
 	case "access":
-		action = h.checkStat(ctx, ctx.Arg0())
-	case "faccessat", "faccessat2":
-		action = h.checkStat(ctx, ctx.Arg1())
+		action = h.checkStat(ctx, atFdCwd, ctx.Arg0(), 0)
+	case "faccessat":
+		action = h.checkStat(ctx, ctx.Arg0(), ctx.Arg1(), 0)
+	case "faccessat2":
+		action = h.checkStat(ctx, ctx.Arg0(), ctx.Arg1(), ctx.Arg3())
 
 	case "stat", "stat64":
-		action = h.checkStat(ctx, ctx.Arg0())
+		action = h.checkStat(ctx, atFdCwd, ctx.Arg0(), 0)
 	case "lstat", "lstat64":
-		action = h.checkStat(ctx, ctx.Arg0())
-	case "statx", "fstatat", "fstatat64", "newfstatat":
-		action = h.checkStat(ctx, ctx.Arg1())
+		action = h.checkStat(ctx, atFdCwd, ctx.Arg0(), unix.AT_SYMLINK_NOFOLLOW)
+	case "statx":
+		action = h.checkStat(ctx, ctx.Arg0(), ctx.Arg1(), ctx.Arg2())
+	case "fstatat", "fstatat64", "newfstatat":
+		action = h.checkStat(ctx, ctx.Arg0(), ctx.Arg1(), ctx.Arg3())
 
 	case "execve":
-		action = h.checkRead(ctx, ctx.Arg0())
+		action = h.checkRead(ctx, atFdCwd, ctx.Arg0(), 0)
 	case "execveat":
-		action = h.checkRead(ctx, ctx.Arg1())
+		action = h.checkRead(ctx, ctx.Arg0(), ctx.Arg1(), ctx.Arg4())
+
+	case "mkdir", "rmdir":
+		action = h.checkWrite(ctx, atFdCwd, ctx.Arg0(), unix.AT_SYMLINK_NOFOLLOW)
+	case "mkdirat":
+		action = h.checkWrite(ctx, ctx.Arg0(), ctx.Arg1(), unix.AT_SYMLINK_NOFOLLOW)
+	case "mknod":
+		action = h.checkWrite(ctx, atFdCwd, ctx.Arg0(), unix.AT_SYMLINK_NOFOLLOW)
+	case "mknodat":
+		action = h.checkWrite(ctx, ctx.Arg0(), ctx.Arg1(), unix.AT_SYMLINK_NOFOLLOW)
+
+	case "link":
+		// hard link to a file makes it accessible by the new name, thus it
+		// requires write permission on both
+		action = maxAction(
+			h.checkWrite(ctx, atFdCwd, ctx.Arg0(), unix.AT_SYMLINK_NOFOLLOW),
+			h.checkWrite(ctx, atFdCwd, ctx.Arg1(), unix.AT_SYMLINK_NOFOLLOW))
+	case "linkat":
+		oldFlags := uint(unix.AT_SYMLINK_NOFOLLOW)
+		if ctx.Arg4()&unix.AT_SYMLINK_FOLLOW != 0 {
+			oldFlags = 0
+		}
+		action = maxAction(
+			h.checkWrite(ctx, ctx.Arg0(), ctx.Arg1(), oldFlags),
+			h.checkWrite(ctx, ctx.Arg2(), ctx.Arg3(), unix.AT_SYMLINK_NOFOLLOW))
+
+	case "symlink":
+		action = h.checkSymlink(ctx, ctx.Arg0(), atFdCwd, ctx.Arg1())
+	case "symlinkat":
+		action = h.checkSymlink(ctx, ctx.Arg0(), ctx.Arg1(), ctx.Arg2())
 
-	case "chmod":
-		action = h.checkWrite(ctx, ctx.Arg0())
 	case "rename":
-		action = h.checkWrite(ctx, ctx.Arg0())
+		action = maxAction(
+			h.checkWrite(ctx, atFdCwd, ctx.Arg0(), unix.AT_SYMLINK_NOFOLLOW),
+			h.checkWrite(ctx, atFdCwd, ctx.Arg1(), unix.AT_SYMLINK_NOFOLLOW))
+	case "renameat", "renameat2":
+		action = maxAction(
+			h.checkWrite(ctx, ctx.Arg0(), ctx.Arg1(), unix.AT_SYMLINK_NOFOLLOW),
+			h.checkWrite(ctx, ctx.Arg2(), ctx.Arg3(), unix.AT_SYMLINK_NOFOLLOW))
+
+	case "chmod":
+		action = h.checkWrite(ctx, atFdCwd, ctx.Arg0(), 0)
+	case "fchmodat":
+		action = h.checkWrite(ctx, ctx.Arg0(), ctx.Arg1(), 0)
+	case "fchmodat2":
+		action = h.checkWrite(ctx, ctx.Arg0(), ctx.Arg1(), ctx.Arg3())
+
+	case "chown":
+		action = h.checkWrite(ctx, atFdCwd, ctx.Arg0(), 0)
+	case "lchown":
+		action = h.checkWrite(ctx, atFdCwd, ctx.Arg0(), unix.AT_SYMLINK_NOFOLLOW)
+	case "fchownat":
+		action = h.checkWrite(ctx, ctx.Arg0(), ctx.Arg1(), ctx.Arg4())
+
+	case "truncate":
+		action = h.checkWrite(ctx, atFdCwd, ctx.Arg0(), 0)
+
+	case "utime", "utimes":
+		action = h.checkWrite(ctx, atFdCwd, ctx.Arg0(), 0)
+	case "futimesat":
+		action = h.checkWrite(ctx, ctx.Arg0(), ctx.Arg1(), 0)
+	case "utimensat":
+		// NULL path operates on the dirfd as futimens does
+		if ctx.Arg1() == 0 {
+			action = ptracer.TraceAllow
+		} else {
+			action = h.checkWrite(ctx, ctx.Arg0(), ctx.Arg1(), ctx.Arg3())
+		}
+
+	case "chdir":
+		action = h.checkStat(ctx, atFdCwd, ctx.Arg0(), 0)
+
+	case "getxattr", "listxattr":
+		action = h.checkRead(ctx, atFdCwd, ctx.Arg0(), 0)
+	case "lgetxattr", "llistxattr":
+		action = h.checkRead(ctx, atFdCwd, ctx.Arg0(), unix.AT_SYMLINK_NOFOLLOW)
+	case "setxattr", "removexattr":
+		action = h.checkWrite(ctx, atFdCwd, ctx.Arg0(), 0)
+	case "lsetxattr", "lremovexattr":
+		action = h.checkWrite(ctx, atFdCwd, ctx.Arg0(), unix.AT_SYMLINK_NOFOLLOW)
+
+	case "inotify_add_watch":
+		var flags uint
+		if ctx.Arg2()&unix.IN_DONT_FOLLOW != 0 {
+			flags = unix.AT_SYMLINK_NOFOLLOW
+		}
+		action = h.checkRead(ctx, atFdCwd, ctx.Arg1(), flags)
 
 	default:
 		action = h.Handler.CheckSyscall(syscallName)
@@ -124,6 +275,11 @@ func (h *tracerHandler) Handle(ctx *ptracer.Context) ptracer.TraceAction {
 	}
 }
 
+// maxAction returns the most severe action
+func maxAction(a, b ptracer.TraceAction) ptracer.TraceAction {
+	return max(a, b)
+}
+
 func softBanSyscall(ctx *ptracer.Context) ptracer.TraceAction {
 	ctx.SetReturnValue(-int(BanRet))
 	return ptracer.TraceBan
@@ -143,24 +299,51 @@ func getFileMode(flags uint) string {
 }
 
 // getProcCwd gets the process CWD
-func getProcCwd(pid int) string {
+func getProcCwd(pid int) (string, error) {
 	fileName := "/proc/self/cwd"
 	if pid > 0 {
 		fileName = fmt.Sprintf("/proc/%d/cwd", pid)
 	}
-	s, err := os.Readlink(fileName)
+	return os.Readlink(fileName)
+}
+
+// getProcFdPath gets the path of the opened file descriptor of the process
+func getProcFdPath(pid, fd int) (string, error) {
+	fileName := fmt.Sprintf("/proc/self/fd/%d", fd)
+	if pid > 0 {
+		fileName = fmt.Sprintf("/proc/%d/fd/%d", pid, fd)
+	}
+	p, err := os.Readlink(fileName)
 	if err != nil {
-		return ""
+		return "", err
 	}
-	return s
+	// pipe, socket and anonymous inodes are not paths
+	if !filepath.IsAbs(p) {
+		return "", fmt.Errorf("fd %d is not a file: %s", fd, p)
+	}
+	return p, nil
 }
 
-// absPath calculates the absolute path for a process
-// built-in function did the dirty works to resolve relative paths
-func absPath(pid int, p string) string {
-	// if relative path
-	if !filepath.IsAbs(p) {
-		return filepath.Join(getProcCwd(pid), p)
+// resolvePath calculates the absolute path for a process following the
+// semantics of *at syscalls. Relative path is resolved against dirfd, or
+// current work directory if dirfd is AT_FDCWD. Empty path refers to the dirfd
+// itself (AT_EMPTY_PATH). Built-in function did the dirty works to resolve
+// relative paths
+func resolvePath(pid, dirfd int, p string) (string, error) {
+	if filepath.IsAbs(p) {
+		return filepath.Clean(p), nil
+	}
+	var (
+		dir string
+		err error
+	)
+	if dirfd == unix.AT_FDCWD {
+		dir, err = getProcCwd(pid)
+	} else {
+		dir, err = getProcFdPath(pid, dirfd)
+	}
+	if err != nil {
+		return "", err
 	}
-	return filepath.Clean(p)
+	return filepath.Join(dir, p), nil
 }
@@ -0,0 +1,276 @@
+package ptrace
+
+import (
+	"os"
+	"runtime"
+	"slices"
+	"testing"
+	"unsafe"
+
+	"github.com/tobiichi3227/go-sandbox/pkg/seccomp/libseccomp"
+	"github.com/tobiichi3227/go-sandbox/ptracer"
+	"github.com/tobiichi3227/go-sandbox/runner/ptrace/filehandler"
+	"golang.org/x/sys/unix"
+)
+
+// recordHandler records the checked paths and returns the action in actions
+// or allow by default
+type recordHandler struct {
+	checked []string
+	actions map[string]ptracer.TraceAction
+}
+
+func (h *recordHandler) check(kind, p string) ptracer.TraceAction {
+	h.checked = append(h.checked, kind+" "+p)
+	return h.actions[kind+" "+p]
+}
+
+func (h *recordHandler) CheckRead(p string) ptracer.TraceAction    { return h.check("read", p) }
+func (h *recordHandler) CheckWrite(p string) ptracer.TraceAction   { return h.check("write", p) }
+func (h *recordHandler) CheckStat(p string) ptracer.TraceAction    { return h.check("stat", p) }
+func (h *recordHandler) CheckSyscall(s string) ptracer.TraceAction { return h.check("syscall", s) }
+
+// cstrings keeps the C strings used as syscall arguments alive
+type cstrings [][]byte
+
+// addr returns the address of s as a NUL terminated string in this process
+func (c *cstrings) addr(s string) uint {
+	b := append([]byte(s), 0)
+	*c = append(*c, b)
+	return uint(uintptr(unsafe.Pointer(&b[0])))
+}
+
+func TestResolvePath(t *testing.T) {
+	dir, err := os.Open("/etc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dir.Close()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		dirfd    int
+		path     string
+		expected string
+		err      bool
+	}{
+		{"Absolute", int(dir.Fd()), "/usr/../bin/sh", "/bin/sh", false},
+		{"Relative to dirfd", int(dir.Fd()), "passwd", "/etc/passwd", false},
+		{"Parent of dirfd", int(dir.Fd()), "../root", "/root", false},
+		{"Empty path", int(dir.Fd()), "", "/etc", false},
+		{"Relative to cwd", unix.AT_FDCWD, "a/b", cwd + "/a/b", false},
+		{"Not a directory", int(r.Fd()), "a", "", true},
+		{"Bad fd", 1 << 20, "a", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := resolvePath(os.Getpid(), tt.dirfd, tt.path)
+			if tt.err {
+				if err == nil {
+					t.Errorf("resolvePath(%d, %q) = %q; expected error", tt.dirfd, tt.path, p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p != tt.expected {
+				t.Errorf("resolvePath(%d, %q) = %q; expected %q", tt.dirfd, tt.path, p, tt.expected)
+			}
+		})
+	}
+}
+
+func TestHandleOpenAt(t *testing.T) {
+	dir, err := os.Open("/etc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dir.Close()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var strs cstrings
+	defer runtime.KeepAlive(&strs)
+
+	tests := []struct {
+		name    string
+		dirfd   uint
+		path    string
+		flags   uint
+		action  ptracer.TraceAction
+		checked []string
+	}{
+		{"Relative to dirfd", uint(dir.Fd()), "passwd", unix.O_RDONLY, ptracer.TraceAllow, []string{"read /etc/passwd"}},
+		{"Write relative to dirfd", uint(dir.Fd()), "passwd", unix.O_WRONLY, ptracer.TraceAllow, []string{"write /etc/passwd"}},
+		{"Read write", uint(dir.Fd()), "passwd", unix.O_RDWR, ptracer.TraceAllow, []string{"read /etc/passwd", "write /etc/passwd"}},
+		{"Create for read", uint(dir.Fd()), "passwd", unix.O_RDONLY | unix.O_CREAT, ptracer.TraceAllow, []string{"read /etc/passwd", "write /etc/passwd"}},
+		{"Relative to cwd", atFdCwd, "a/b", unix.O_RDONLY, ptracer.TraceAllow, []string{"read " + cwd + "/a/b"}},
+		{"Absolute", uint(r.Fd()), "/etc/passwd", unix.O_RDONLY, ptracer.TraceAllow, []string{"read /etc/passwd"}},
+		{"Not a directory", uint(r.Fd()), "passwd", unix.O_RDONLY, ptracer.TraceKill, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rh := &recordHandler{}
+			h := &tracerHandler{Handler: rh}
+			ctx := ptracer.NewContext(os.Getpid(), unix.SYS_OPENAT, tt.dirfd, strs.addr(tt.path), tt.flags)
+			if action := h.Handle(ctx); action != tt.action {
+				t.Errorf("openat(%d, %q) = %v; expected %v", int(int32(tt.dirfd)), tt.path, action, tt.action)
+			}
+			if !slices.Equal(rh.checked, tt.checked) {
+				t.Errorf("openat(%d, %q) checked %q; expected %q", int(int32(tt.dirfd)), tt.path, rh.checked, tt.checked)
+			}
+		})
+	}
+}
+
+func TestHandlePathSyscalls(t *testing.T) {
+	dir, err := os.Open("/etc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dir.Close()
+
+	var strs cstrings
+	defer runtime.KeepAlive(&strs)
+
+	var (
+		d     = uint(dir.Fd())
+		a     = strs.addr("/tmp/a")
+		b     = strs.addr("/tmp/b")
+		x     = strs.addr("x")
+		y     = strs.addr("y")
+		c     = strs.addr("c")
+		abs   = strs.addr("/etc/passwd")
+		allow = ptracer.TraceAllow
+		ban   = ptracer.TraceBan
+		kill  = ptracer.TraceKill
+	)
+
+	tests := []struct {
+		syscall string
+		args    []uint
+		actions map[string]ptracer.TraceAction
+		action  ptracer.TraceAction
+		checked []string
+	}{
+		{"mkdir", []uint{a, 0o755}, nil, allow, []string{"write /tmp/a"}},
+		{"mkdirat", []uint{d, x, 0o755}, nil, allow, []string{"write /etc/x"}},
+		{"rmdir", []uint{a}, nil, allow, []string{"write /tmp/a"}},
+		{"mknod", []uint{a, 0, 0}, nil, allow, []string{"write /tmp/a"}},
+		{"mknodat", []uint{d, x, 0, 0}, nil, allow, []string{"write /etc/x"}},
+		{"link", []uint{a, b}, nil, allow, []string{"write /tmp/a", "write /tmp/b"}},
+		{"linkat", []uint{d, x, atFdCwd, b, 0}, nil, allow, []string{"write /etc/x", "write /tmp/b"}},
+		{"symlink", []uint{c, a}, nil, allow, []string{"write /tmp/a", "write /tmp/c"}},
+		{"symlinkat", []uint{abs, d, x}, nil, allow, []string{"write /etc/x", "write /etc/passwd"}},
+		{"rename", []uint{a, b}, nil, allow, []string{"write /tmp/a", "write /tmp/b"}},
+		{"renameat", []uint{d, x, d, y}, nil, allow, []string{"write /etc/x", "write /etc/y"}},
+		{"renameat2", []uint{d, x, atFdCwd, b, 0}, nil, allow, []string{"write /etc/x", "write /tmp/b"}},
+		{"chown", []uint{a, 0, 0}, nil, allow, []string{"write /tmp/a"}},
+		{"lchown", []uint{a, 0, 0}, nil, allow, []string{"write /tmp/a"}},
+		{"fchownat", []uint{d, x, 0, 0, 0}, nil, allow, []string{"write /etc/x"}},
+		{"truncate", []uint{a, 0}, nil, allow, []string{"write /tmp/a"}},
+		{"utimensat", []uint{d, x, 0, 0}, nil, allow, []string{"write /etc/x"}},
+		{"utimensat", []uint{d, 0, 0, 0}, nil, allow, nil},
+		{"chdir", []uint{a}, nil, allow, []string{"stat /tmp/a"}},
+		{"getxattr", []uint{a, b, 0, 0}, nil, allow, []string{"read /tmp/a"}},
+		{"lgetxattr", []uint{a, b, 0, 0}, nil, allow, []string{"read /tmp/a"}},
+		{"listxattr", []uint{a, 0, 0}, nil, allow, []string{"read /tmp/a"}},
+		{"setxattr", []uint{a, b, 0, 0, 0}, nil, allow, []string{"write /tmp/a"}},
+		{"lsetxattr", []uint{a, b, 0, 0, 0}, nil, allow, []string{"write /tmp/a"}},
+		{"removexattr", []uint{a, b}, nil, allow, []string{"write /tmp/a"}},
+		{"inotify_add_watch", []uint{0, a, unix.IN_MODIFY}, nil, allow, []string{"read /tmp/a"}},
+		{"statx", []uint{d, x, 0, 0, 0}, nil, allow, []string{"stat /etc/x"}},
+		{"faccessat2", []uint{d, x, 0, 0}, nil, allow, []string{"stat /etc/x"}},
+
+		// the stricter action of both paths is taken
+		{"rename", []uint{a, b}, map[string]ptracer.TraceAction{"write /tmp/b": ban}, ban, []string{"write /tmp/a", "write /tmp/b"}},
+		{"renameat", []uint{d, x, d, y}, map[string]ptracer.TraceAction{"write /etc/x": kill, "write /etc/y": ban}, kill, []string{"write /etc/x", "write /etc/y"}},
+		{"linkat", []uint{d, x, atFdCwd, b, 0}, map[string]ptracer.TraceAction{"write /etc/x": ban}, ban, []string{"write /etc/x", "write /tmp/b"}},
+		{"symlink", []uint{c, a}, map[string]ptracer.TraceAction{"write /tmp/c": kill}, kill, []string{"write /tmp/a", "write /tmp/c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.syscall, func(t *testing.T) {
+			no, err := libseccomp.ToSyscallNo(tt.syscall)
+			if err != nil {
+				t.Skip(err)
+			}
+			rh := &recordHandler{actions: tt.actions}
+			h := &tracerHandler{Handler: rh}
+			ctx := ptracer.NewContext(os.Getpid(), no, tt.args...)
+			if action := h.Handle(ctx); action != tt.action {
+				t.Errorf("%s = %v; expected %v", tt.syscall, action, tt.action)
+			}
+			if !slices.Equal(rh.checked, tt.checked) {
+				t.Errorf("%s checked %q; expected %q", tt.syscall, rh.checked, tt.checked)
+			}
+		})
+	}
+}
+
+func TestMaxAction(t *testing.T) {
+	actions := []ptracer.TraceAction{ptracer.TraceAllow, ptracer.TraceBan, ptracer.TraceKill}
+	for i, a := range actions {
+		for j, b := range actions {
+			if got, expected := maxAction(a, b), actions[max(i, j)]; got != expected {
+				t.Errorf("maxAction(%v, %v) = %v; expected %v", a, b, got, expected)
+			}
+		}
+	}
+}
+
+// TestHandleOpenAt_ReadDenied checks that the read deny entries cannot be
+// bypassed by opening the writable file for read and write
+func TestHandleOpenAt_ReadDenied(t *testing.T) {
+	fs := filehandler.NewFileSets()
+	fs.Writable.AddRange([]string{"/w/"}, "/w")
+	fs.Readable.AddRange([]string{"!/w/secret"}, "/w")
+	h := &tracerHandler{Handler: &filehandler.Handler{FileSet: fs}}
+
+	var strs cstrings
+	defer runtime.KeepAlive(&strs)
+
+	tests := []struct {
+		name   string
+		path   string
+		flags  uint
+		action ptracer.TraceAction
+	}{
+		{"Read", "/w/secret", unix.O_RDONLY, ptracer.TraceKill},
+		{"Write", "/w/secret", unix.O_WRONLY, ptracer.TraceAllow},
+		{"Read write", "/w/secret", unix.O_RDWR, ptracer.TraceKill},
+		{"Create for read", "/w/secret", unix.O_RDONLY | unix.O_CREAT, ptracer.TraceKill},
+		{"Truncate for read", "/w/secret", unix.O_RDONLY | unix.O_TRUNC, ptracer.TraceKill},
+		{"Read write allowed", "/w/a", unix.O_RDWR, ptracer.TraceAllow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := ptracer.NewContext(os.Getpid(), unix.SYS_OPENAT, atFdCwd, strs.addr(tt.path), tt.flags)
+			if action := h.Handle(ctx); action != tt.action {
+				t.Errorf("openat(%q, %#x) = %v; expected %v", tt.path, tt.flags, action, tt.action)
+			}
+		})
+	}
+}
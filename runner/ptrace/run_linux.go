@@ -16,6 +16,7 @@ func (r *Runner) Run(c context.Context) runner.Result {
 		Env:      r.Env,
 		ExecFile: r.ExecFile,
 		RLimits:  r.RLimits,
+		Sched:    r.Sched,
 		Files:    r.Files,
 		WorkDir:  r.WorkDir,
 		Seccomp:  r.Seccomp.SockFprog(),
@@ -35,6 +36,8 @@ func (r *Runner) Run(c context.Context) runner.Result {
 		Handler: th,
 		Runner:  ch,
 		Limit:   r.Limit,
+
+		FreezeOnLimit: r.FreezeOnLimit,
 	}
 	return tracer.Trace(c)
 }
@@ -3,6 +3,7 @@ package ptrace
 import (
 	"syscall"
 
+	"github.com/tobiichi3227/go-sandbox/pkg/forkexec"
 	"github.com/tobiichi3227/go-sandbox/pkg/rlimit"
 	"github.com/tobiichi3227/go-sandbox/pkg/seccomp"
 	"github.com/tobiichi3227/go-sandbox/ptracer"
@@ -26,6 +27,9 @@ type Runner struct {
 	// Resource limit set by set rlimit
 	RLimits []rlimit.RLimit
 
+	// CPU affinity, scheduling policy and I/O priority
+	Sched *forkexec.Sched
+
 	// Res limit enforced by tracer
 	Limit runner.Limit
 
@@ -43,6 +47,10 @@ type Runner struct {
 
 	// Use by cgroup to add proc
 	SyncFunc func(pid int) error
+
+	// FreezeOnLimit freezes the program before kill when the limit hit or the
+	// context is canceled
+	FreezeOnLimit *runner.FreezeOnLimit
 }
 
 // BanRet defines the return value for a syscall ban action
@@ -55,3 +63,12 @@ type Handler interface {
 	CheckStat(string) ptracer.TraceAction
 	CheckSyscall(string) ptracer.TraceAction
 }
+
+// NoFollowHandler is optionally implemented by Handler to check the path
+// accessed by syscalls that do not follow the final symbolic link (e.g. lstat,
+// unlink or O_NOFOLLOW). Handler.CheckXxx is used when it is not implemented
+type NoFollowHandler interface {
+	CheckReadNoFollow(string) ptracer.TraceAction
+	CheckWriteNoFollow(string) ptracer.TraceAction
+	CheckStatNoFollow(string) ptracer.TraceAction
+}
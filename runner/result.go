@@ -1,6 +1,7 @@
 package runner
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -11,6 +12,10 @@ type Result struct {
 	ExitStatus int    // exit status (signal number if signalled)
 	Error      string // potential detailed error message (for program runner error)
 
+	RuntimeError RuntimeError // classified cause of runtime error (e.g. stack overflow)
+
+	MismatchOffset int64 // output byte offset of the first mismatch (wrong answer)
+
 	Time     time.Duration // used user CPU time  (underlying type int64 in ns)
 	Memory   Size          // used user memory    (underlying type uint64 in bytes)
 	ProcPeak uint64        // maximum processes
@@ -26,8 +31,14 @@ func (r Result) String() string {
 		return fmt.Sprintf("Result[%v %v][%v %v]", r.Time, r.Memory, r.SetUpTime, r.RunningTime)
 
 	case StatusSignalled:
+		if r.RuntimeError != RuntimeErrorNone {
+			return fmt.Sprintf("Result[Signalled(%d %v)][%v %v][%v %v]", r.ExitStatus, r.RuntimeError, r.Time, r.Memory, r.SetUpTime, r.RunningTime)
+		}
 		return fmt.Sprintf("Result[Signalled(%d)][%v %v][%v %v]", r.ExitStatus, r.Time, r.Memory, r.SetUpTime, r.RunningTime)
 
+	case StatusWrongAnswer:
+		return fmt.Sprintf("Result[WrongAnswer(at %d)][%v %v][%v %v]", r.MismatchOffset, r.Time, r.Memory, r.SetUpTime, r.RunningTime)
+
 	case StatusRunnerError:
 		return fmt.Sprintf("Result[RunnerFailed(%s)][%v %v][%v %v]", r.Error, r.Time, r.Memory, r.SetUpTime, r.RunningTime)
 
@@ -35,3 +46,56 @@ func (r Result) String() string {
 		return fmt.Sprintf("Result[%v(%s %d)][%v %v][%v %v]", r.Status, r.Error, r.ExitStatus, r.Time, r.Memory, r.SetUpTime, r.RunningTime)
 	}
 }
+
+// resultJSON is the wire form of Result, durations are in ns and sizes are
+// in bytes
+type resultJSON struct {
+	Status         Status        `json:"status"`
+	ExitStatus     int           `json:"exitStatus"`
+	Error          string        `json:"error,omitempty"`
+	RuntimeError   RuntimeError  `json:"runtimeError,omitempty"`
+	MismatchOffset int64         `json:"mismatchOffset,omitempty"`
+	Time           time.Duration `json:"time"`
+	Memory         uint64        `json:"memory"`
+	ProcPeak       uint64        `json:"procPeak,omitempty"`
+	SetUpTime      time.Duration `json:"setUpTime"`
+	RunningTime    time.Duration `json:"runningTime"`
+}
+
+// MarshalJSON encodes the result in a stable form, it is required since the
+// embedded Status is a TextMarshaler
+func (r Result) MarshalJSON() ([]byte, error) {
+	return json.Marshal(resultJSON{
+		Status:         r.Status,
+		ExitStatus:     r.ExitStatus,
+		Error:          r.Error,
+		RuntimeError:   r.RuntimeError,
+		MismatchOffset: r.MismatchOffset,
+		Time:           r.Time,
+		Memory:         uint64(r.Memory),
+		ProcPeak:       r.ProcPeak,
+		SetUpTime:      r.SetUpTime,
+		RunningTime:    r.RunningTime,
+	})
+}
+
+// UnmarshalJSON decodes the result encoded by MarshalJSON
+func (r *Result) UnmarshalJSON(b []byte) error {
+	var j resultJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*r = Result{
+		Status:         j.Status,
+		ExitStatus:     j.ExitStatus,
+		Error:          j.Error,
+		RuntimeError:   j.RuntimeError,
+		MismatchOffset: j.MismatchOffset,
+		Time:           j.Time,
+		Memory:         Size(j.Memory),
+		ProcPeak:       j.ProcPeak,
+		SetUpTime:      j.SetUpTime,
+		RunningTime:    j.RunningTime,
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatusText(t *testing.T) {
+	names := []string{"INVALID", "OK", "TLE", "MLE", "OLE", "BAN", "SIG", "NZEC", "ERR", "WA"}
+	for i, name := range names {
+		s := Status(i)
+		if s.Name() != name {
+			t.Errorf("Status(%d).Name() = %s; expected %s", i, s.Name(), name)
+		}
+		b, err := s.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var u Status
+		if err := u.UnmarshalText(b); err != nil {
+			t.Errorf("UnmarshalText(%s) failed: %v", b, err)
+		} else if u != s {
+			t.Errorf("UnmarshalText(%s) = %d; expected %d", b, u, s)
+		}
+	}
+	if n := Status(len(names)).Name(); n != "INVALID" {
+		t.Errorf("out of range status name = %s; expected INVALID", n)
+	}
+
+	tests := []struct {
+		str      string
+		expected Status
+		err      bool
+	}{
+		{"tle", StatusTimeLimitExceeded, false},
+		{"Time Limit Exceeded", StatusTimeLimitExceeded, false},
+		{"nonzero exit status", StatusNonzeroExitStatus, false},
+		{"wa", StatusWrongAnswer, false},
+		{"", 0, true},
+		{"XYZ", 0, true},
+	}
+	for _, tt := range tests {
+		s, err := ParseStatus(tt.str)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseStatus(%q) = %v; expected error", tt.str, s)
+			}
+			continue
+		}
+		if err != nil || s != tt.expected {
+			t.Errorf("ParseStatus(%q) = %v, %v; expected %v", tt.str, s, err, tt.expected)
+		}
+	}
+}
+
+func TestResultJSON(t *testing.T) {
+	tests := []struct {
+		result   Result
+		contains []string
+	}{
+		{
+			Result{Status: StatusNormal, Time: time.Second, Memory: 64 << 20, SetUpTime: time.Millisecond, RunningTime: 2 * time.Second},
+			[]string{`"status":"OK"`, `"time":1000000000`, `"memory":67108864`},
+		},
+		{
+			Result{Status: StatusSignalled, ExitStatus: 11, RuntimeError: RuntimeErrorStackOverflow, Time: time.Millisecond, ProcPeak: 2},
+			[]string{`"status":"SIG"`, `"exitStatus":11`, `"runtimeError":"STACK"`, `"procPeak":2`},
+		},
+		{
+			Result{Status: StatusNonzeroExitStatus, ExitStatus: 1, RuntimeError: RuntimeErrorUncaughtException},
+			[]string{`"status":"NZEC"`, `"runtimeError":"EXCEPTION"`},
+		},
+		{
+			Result{Status: StatusRunnerError, Error: "failed"},
+			[]string{`"status":"ERR"`, `"error":"failed"`},
+		},
+		{
+			Result{Status: StatusWrongAnswer, MismatchOffset: 42},
+			[]string{`"status":"WA"`, `"mismatchOffset":42`},
+		},
+	}
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range tt.contains {
+			if !strings.Contains(string(b), c) {
+				t.Errorf("json %s does not contain %s", b, c)
+			}
+		}
+		var r Result
+		if err := json.Unmarshal(b, &r); err != nil {
+			t.Errorf("unmarshal %s failed: %v", b, err)
+		} else if r != tt.result {
+			t.Errorf("unmarshal %s = %+v; expected %+v", b, r, tt.result)
+		}
+	}
+
+	var r Result
+	if err := json.Unmarshal([]byte(`{"status":"XYZ"}`), &r); err == nil {
+		t.Error("expected error for unknown status")
+	}
+	if err := json.Unmarshal([]byte(`{"status":"OK","runtimeError":"XYZ"}`), &r); err == nil {
+		t.Error("expected error for unknown runtime error")
+	}
+}
+
+func TestLimitJSON(t *testing.T) {
+	l := Limit{TimeLimit: 1500 * time.Millisecond, MemoryLimit: 256 << 20}
+	b, err := json.Marshal(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"timeLimit":1500000000,"memoryLimit":268435456}`; string(b) != expected {
+		t.Errorf("json = %s; expected %s", b, expected)
+	}
+	var u Limit
+	if err := json.Unmarshal(b, &u); err != nil {
+		t.Fatal(err)
+	}
+	if u != l {
+		t.Errorf("unmarshal %s = %v; expected %v", b, u, l)
+	}
+}
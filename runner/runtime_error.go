@@ -0,0 +1,109 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RuntimeError is the sub-classification of the runtime error (Signalled /
+// Nonzero Exit Status) to give actionable verdicts
+type RuntimeError int
+
+// Runtime error causes
+const (
+	RuntimeErrorNone               RuntimeError = iota // 0 not classified
+	RuntimeErrorSegmentationFault                      // 1 SIGSEGV
+	RuntimeErrorStackOverflow                          // 2 SIGSEGV near the stack guard
+	RuntimeErrorAbort                                  // 3 SIGABRT (e.g. assert)
+	RuntimeErrorDivideByZero                           // 4 SIGFPE integer / float divide by zero
+	RuntimeErrorArithmeticOverflow                     // 5 SIGFPE integer / float overflow
+	RuntimeErrorFloatingPoint                          // 6 other SIGFPE
+	RuntimeErrorBusError                               // 7 SIGBUS
+	RuntimeErrorIllegalInstruction                     // 8 SIGILL
+	RuntimeErrorUncaughtException                      // 9 uncaught exception by exit code
+)
+
+var (
+	runtimeErrorString = []string{
+		"",
+		"Segmentation Fault",
+		"Stack Overflow",
+		"Abort",
+		"Divide By Zero",
+		"Arithmetic Overflow",
+		"Floating Point Exception",
+		"Bus Error",
+		"Illegal Instruction",
+		"Uncaught Exception",
+	}
+
+	// runtimeErrorName are the stable machine names used by MarshalText
+	runtimeErrorName = []string{
+		"",
+		"SEGV",
+		"STACK",
+		"ABORT",
+		"DIVZERO",
+		"OVERFLOW",
+		"FPE",
+		"BUS",
+		"ILL",
+		"EXCEPTION",
+	}
+)
+
+func (e RuntimeError) String() string {
+	i := int(e)
+	if i >= 0 && i < len(runtimeErrorString) {
+		return runtimeErrorString[i]
+	}
+	return runtimeErrorString[0]
+}
+
+// Name returns the stable machine name of the runtime error (e.g. "STACK")
+func (e RuntimeError) Name() string {
+	i := int(e)
+	if i >= 0 && i < len(runtimeErrorName) {
+		return runtimeErrorName[i]
+	}
+	return runtimeErrorName[0]
+}
+
+// MarshalText encodes the runtime error as its machine name
+func (e RuntimeError) MarshalText() ([]byte, error) {
+	return []byte(e.Name()), nil
+}
+
+// UnmarshalText parses the runtime error from its machine name, case insensitive
+func (e *RuntimeError) UnmarshalText(text []byte) error {
+	for i, n := range runtimeErrorName {
+		if strings.EqualFold(string(text), n) {
+			*e = RuntimeError(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("runner: unknown runtime error %q", text)
+}
+
+// ExitCodeClassifier classifies the nonzero exit status by exit code, for
+// managed runtimes that report uncaught exception by exit code
+type ExitCodeClassifier map[int]RuntimeError
+
+var (
+	// JavaExitCodes classifies the exit code of JVM (1 on uncaught exception),
+	// notice that a plain System.exit(1) is also labeled as uncaught exception
+	JavaExitCodes = ExitCodeClassifier{1: RuntimeErrorUncaughtException}
+
+	// PythonExitCodes classifies the exit code of CPython (1 on uncaught
+	// exception), notice that a plain sys.exit(1) is also labeled as uncaught
+	// exception
+	PythonExitCodes = ExitCodeClassifier{1: RuntimeErrorUncaughtException}
+)
+
+// Classify sets the runtime error of the result with nonzero exit status
+func (c ExitCodeClassifier) Classify(r *Result) {
+	if r.Status != StatusNonzeroExitStatus || r.RuntimeError != RuntimeErrorNone {
+		return
+	}
+	r.RuntimeError = c[r.ExitStatus]
+}
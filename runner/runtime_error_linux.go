@@ -0,0 +1,36 @@
+package runner
+
+import "syscall"
+
+// si_code of SIGFPE
+const (
+	fpeIntDiv = 1
+	fpeIntOvf = 2
+	fpeFltDiv = 3
+	fpeFltOvf = 4
+)
+
+// ClassifySignal classifies the runtime error from the signal and its
+// si_code (0 if not available). Stack overflow could not be told from
+// signal and should be checked by the fault address (e.g. IsStackFault)
+func ClassifySignal(sig syscall.Signal, code int32) RuntimeError {
+	switch sig {
+	case syscall.SIGSEGV:
+		return RuntimeErrorSegmentationFault
+	case syscall.SIGABRT:
+		return RuntimeErrorAbort
+	case syscall.SIGFPE:
+		switch code {
+		case fpeIntDiv, fpeFltDiv:
+			return RuntimeErrorDivideByZero
+		case fpeIntOvf, fpeFltOvf:
+			return RuntimeErrorArithmeticOverflow
+		}
+		return RuntimeErrorFloatingPoint
+	case syscall.SIGBUS:
+		return RuntimeErrorBusError
+	case syscall.SIGILL:
+		return RuntimeErrorIllegalInstruction
+	}
+	return RuntimeErrorNone
+}
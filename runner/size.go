@@ -2,7 +2,10 @@ package runner
 
 import (
 	"fmt"
+	"math"
 	"strconv"
+	"strings"
+	"unicode"
 )
 
 // Size stores number of byte for the object. E.g. Memory.
@@ -24,32 +27,94 @@ func (s Size) String() string {
 	}
 }
 
-// Set parse the size value from string
+// Set parse the size value from string, see ParseSize
 func (s *Size) Set(str string) error {
-	switch str[len(str)-1] {
-	case 'b', 'B':
-		str = str[:len(str)-1]
+	t, err := ParseSize(str)
+	if err != nil {
+		return err
 	}
+	*s = t
+	return nil
+}
 
-	factor := 0
-	switch str[len(str)-1] {
-	case 'k', 'K':
-		factor = 10
-		str = str[:len(str)-1]
-	case 'm', 'M':
-		factor = 20
-		str = str[:len(str)-1]
-	case 'g', 'G':
-		factor = 30
-		str = str[:len(str)-1]
-	}
+// ParseSize parses size with optional decimal and unit, single letter unit
+// and IEC unit are binary (e.g. "64m", "1.5GiB") and SI unit is decimal
+// (e.g. "512MB")
+func ParseSize(str string) (Size, error) {
+	s := strings.TrimSpace(str)
+	num := strings.TrimRightFunc(s, unicode.IsLetter)
+	unit := s[len(num):]
+	num = strings.TrimSpace(num)
 
-	t, err := strconv.Atoi(str)
+	mul, err := sizeUnit(unit)
 	if err != nil {
-		return err
+		return 0, fmt.Errorf("runner: size %q: %w", str, err)
 	}
-	*s = Size(t << factor)
-	return nil
+	if n, err := strconv.ParseUint(num, 10, 64); err == nil {
+		if n > math.MaxUint64/mul {
+			return 0, fmt.Errorf("runner: size %q overflows", str)
+		}
+		return Size(n * mul), nil
+	}
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, fmt.Errorf("runner: invalid size %q", str)
+	}
+	v := math.Round(f * float64(mul))
+	if v >= math.MaxUint64 {
+		return 0, fmt.Errorf("runner: size %q overflows", str)
+	}
+	return Size(v), nil
+}
+
+// sizeUnits are the unit prefixes in increasing order
+const sizeUnits = "kmgtpe"
+
+// sizeUnit returns the multiplier of the unit
+func sizeUnit(unit string) (uint64, error) {
+	u := strings.ToLower(unit)
+	if u == "" || u == "b" {
+		return 1, nil
+	}
+	i := strings.IndexByte(sizeUnits, u[0])
+	if i < 0 {
+		return 0, fmt.Errorf("unknown unit %q", unit)
+	}
+	var base uint64
+	switch u[1:] {
+	case "", "ib":
+		base = 1 << 10
+	case "b":
+		base = 1000
+	default:
+		return 0, fmt.Errorf("unknown unit %q", unit)
+	}
+	mul := uint64(1)
+	for ; i >= 0; i-- {
+		mul *= base
+	}
+	return mul, nil
+}
+
+// MarshalText encodes the size losslessly in the largest IEC unit that
+// divides it (e.g. "512MiB", "1536KiB", "100B")
+func (s Size) MarshalText() ([]byte, error) {
+	t := uint64(s)
+	if t == 0 {
+		return []byte("0B"), nil
+	}
+	for i := len(sizeUnits) - 1; i >= 0; i-- {
+		shift := 10 * uint(i+1)
+		if t%(1<<shift) == 0 {
+			return fmt.Appendf(nil, "%d%ciB", t>>shift, sizeUnits[i]-'a'+'A'), nil
+		}
+	}
+	return fmt.Appendf(nil, "%dB", t), nil
+}
+
+// UnmarshalText parses the size by ParseSize
+func (s *Size) UnmarshalText(text []byte) error {
+	return s.Set(string(text))
 }
 
 // Byte return size in bytes
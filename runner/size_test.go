@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		str      string
+		expected Size
+		err      bool
+	}{
+		{"0", 0, false},
+		{"100", 100, false},
+		{"100B", 100, false},
+		{"1k", 1 << 10, false},
+		{"64m", 64 << 20, false},
+		{"64M", 64 << 20, false},
+		{"1.5GiB", 3 << 29, false},
+		{"1.5 GiB", 3 << 29, false},
+		{"512MiB", 512 << 20, false},
+		{"512MB", 512_000_000, false},
+		{"64mb", 64_000_000, false},
+		{"2kB", 2000, false},
+		{"15EiB", 15 << 60, false},
+		{"16EiB", 0, true},
+		{"18446744073709551616", 0, true},
+		{"1e30GiB", 0, true},
+		{"", 0, true},
+		{" ", 0, true},
+		{"MiB", 0, true},
+		{"-1", 0, true},
+		{"1.5x", 0, true},
+		{"1ZiB", 0, true},
+	}
+	for _, tt := range tests {
+		s, err := ParseSize(tt.str)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseSize(%q) = %d; expected error", tt.str, s)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSize(%q) failed: %v", tt.str, err)
+			continue
+		}
+		if s != tt.expected {
+			t.Errorf("ParseSize(%q) = %d; expected %d", tt.str, s, tt.expected)
+		}
+	}
+}
+
+func TestSizeText(t *testing.T) {
+	tests := []struct {
+		size Size
+		text string
+	}{
+		{0, "0B"},
+		{100, "100B"},
+		{1 << 10, "1KiB"},
+		{1536 << 10, "1536KiB"},
+		{512 << 20, "512MiB"},
+		{3 << 29, "1536MiB"},
+		{512_000_000, "500000KiB"},
+		{1<<64 - 1, "18446744073709551615B"},
+		{15 << 60, "15EiB"},
+	}
+	for _, tt := range tests {
+		b, err := tt.size.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.text {
+			t.Errorf("MarshalText(%d) = %s; expected %s", tt.size, b, tt.text)
+		}
+		var s Size
+		if err := s.UnmarshalText(b); err != nil {
+			t.Errorf("UnmarshalText(%s) failed: %v", b, err)
+		} else if s != tt.size {
+			t.Errorf("UnmarshalText(%s) = %d; expected %d", b, s, tt.size)
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package runner
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Status is the result Status
 type Status int
 
@@ -23,6 +28,9 @@ const (
 
 	// Programmer Runner Error
 	StatusRunnerError // 8 runner error
+
+	// Output mismatched the expected output
+	StatusWrongAnswer // 9 wrong answer
 )
 
 var (
@@ -36,6 +44,21 @@ var (
 		"Signalled",
 		"Nonzero Exit Status",
 		"Runner Error",
+		"Wrong Answer",
+	}
+
+	// statusName are the stable machine names used by MarshalText
+	statusName = []string{
+		"INVALID",
+		"OK",
+		"TLE",
+		"MLE",
+		"OLE",
+		"BAN",
+		"SIG",
+		"NZEC",
+		"ERR",
+		"WA",
 	}
 )
 
@@ -50,3 +73,38 @@ func (t Status) String() string {
 func (t Status) Error() string {
 	return t.String()
 }
+
+// Name returns the stable machine name of the status (e.g. "TLE")
+func (t Status) Name() string {
+	i := int(t)
+	if i >= 0 && i < len(statusName) {
+		return statusName[i]
+	}
+	return statusName[0]
+}
+
+// ParseStatus parses the status from its machine name or its string, case
+// insensitive
+func ParseStatus(s string) (Status, error) {
+	for i := range statusName {
+		if strings.EqualFold(s, statusName[i]) || (statusString[i] != "" && strings.EqualFold(s, statusString[i])) {
+			return Status(i), nil
+		}
+	}
+	return StatusInvalid, fmt.Errorf("runner: unknown status %q", s)
+}
+
+// MarshalText encodes the status as its machine name
+func (t Status) MarshalText() ([]byte, error) {
+	return []byte(t.Name()), nil
+}
+
+// UnmarshalText parses the status by ParseStatus
+func (t *Status) UnmarshalText(text []byte) error {
+	s, err := ParseStatus(string(text))
+	if err != nil {
+		return err
+	}
+	*t = s
+	return nil
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/tobiichi3227/go-sandbox/pkg/forkexec"
 	"github.com/tobiichi3227/go-sandbox/pkg/mount"
+	"github.com/tobiichi3227/go-sandbox/pkg/pidfd"
 	"github.com/tobiichi3227/go-sandbox/runner"
 )
 
@@ -20,8 +21,12 @@ const (
 
 // Run starts the unshared process
 func (r *Runner) Run(c context.Context) (result runner.Result) {
-	maskPathM := make([]mount.SyscallParams, 0, len(r.MaskPaths))
-	for _, path := range r.MaskPaths {
+	maskPaths := r.MaskPaths
+	if maskPaths == nil {
+		maskPaths = mount.DefaultMaskPaths
+	}
+	maskPathM := make([]mount.SyscallParams, 0, len(maskPaths))
+	for _, path := range maskPaths {
 		syscall, err := (&mount.Mount{
 			Source: "",
 			Target: path,
@@ -37,9 +42,13 @@ func (r *Runner) Run(c context.Context) (result runner.Result) {
 		Env:            r.Env,
 		ExecFile:       r.ExecFile,
 		RLimits:        r.RLimits,
+		Sched:          r.Sched,
+		DisableASLR:    r.DisableASLR,
+		TimeOffsets:    r.TimeOffsets,
 		Files:          r.Files,
 		WorkDir:        r.WorkDir,
 		Seccomp:        r.Seccomp.SockFprog(),
+		Landlock:       r.Landlock,
 		NoNewPrivs:     true,
 		CloneFlags:     UnshareFlags,
 		Mounts:         r.Mounts,
@@ -62,35 +71,71 @@ func (r *Runner) Run(c context.Context) (result runner.Result) {
 		fTime   time.Time    // finish time for setup
 	)
 
-	// Start the runner
+	// Start the runner, pidfd is -1 if not supported
+	pidFd := -1
+	ch.PidFd = &pidFd
 	pgid, err := ch.Start()
-	r.println("Starts: ", pgid, err)
+	r.println("Starts: ", pgid, pidFd, err)
 	if err != nil {
 		result.Status = runner.StatusRunnerError
 		result.Error = err.Error()
 		return
 	}
+	// forkexec returns after the execve
+	observer := runner.ObserverFromContext(c)
+	observer.OnStart(pgid)
+	observer.OnExec()
+
+	// the child is the init of the new pid namespace, thus killing it kills all
+	// the processes inside. pidfd ensures it never hit an unrelated process
+	kill := func() {
+		if pidFd >= 0 {
+			pidfd.Kill(pidFd, unix.SIGKILL)
+		} else {
+			killAll(pgid)
+		}
+	}
 
 	ctx, cancel := context.WithCancel(c)
-	defer cancel()
+	killDone := make(chan struct{})
 
 	// handle cancel
 	go func() {
+		defer close(killDone)
 		<-ctx.Done()
-		killAll(pgid)
+		// the parent context is canceled by the caller upon limit hit
+		if c.Err() != nil {
+			if c.Err() == context.DeadlineExceeded {
+				observer.OnLimitHit(runner.StatusTimeLimitExceeded)
+			}
+			r.FreezeOnLimit.Kill(kill)
+		} else {
+			kill()
+		}
 	}()
 
 	// kill all tracee upon return
 	defer func() {
-		killAll(pgid)
-		collectZombie(pgid)
+		// ensure cancel handler finished before reaping and closing the pidfd
+		cancel()
+		<-killDone
+		kill()
+		if pidFd >= 0 {
+			unix.Close(pidFd)
+		} else {
+			collectZombie(pgid)
+		}
 		result.SetUpTime = fTime.Sub(sTime)
 		result.RunningTime = time.Since(fTime)
 	}()
 
 	fTime = time.Now()
 	for {
-		_, err := unix.Wait4(pgid, &wstatus, 0, &rusage)
+		if pidFd >= 0 {
+			wstatus, err = pidfd.Wait(pidFd, &rusage)
+		} else {
+			_, err = unix.Wait4(pgid, &wstatus, 0, &rusage)
+		}
 		if err == unix.EINTR {
 			continue
 		}
@@ -134,7 +179,11 @@ func (r *Runner) Run(c context.Context) (result runner.Result) {
 			Time:       userTime,
 			Memory:     userMem,
 		}
+		if status == runner.StatusSignalled {
+			result.RuntimeError = runner.ClassifySignal(wstatus.Signal(), 0)
+		}
 		if status != runner.StatusNormal {
+			limitHit(c, observer, status)
 			return
 		}
 
@@ -161,11 +210,27 @@ func (r *Runner) Run(c context.Context) (result runner.Result) {
 			}
 			result.Status = status
 			result.ExitStatus = int(sig)
+			limitHit(c, observer, status)
+			if status == runner.StatusSignalled {
+				result.RuntimeError = runner.ClassifySignal(sig, 0)
+			}
 			return
 		}
 	}
 }
 
+// limitHit notifies the observer of the limit hit detected after exit unless
+// the caller has canceled the run, which notifies the observer by itself
+func limitHit(c context.Context, observer runner.Observer, status runner.Status) {
+	if c.Err() != nil {
+		return
+	}
+	switch status {
+	case runner.StatusTimeLimitExceeded, runner.StatusMemoryLimitExceeded, runner.StatusOutputLimitExceeded:
+		observer.OnLimitHit(status)
+	}
+}
+
 // kill all tracee according to pids
 func killAll(pgid int) {
 	unix.Kill(-pgid, unix.SIGKILL)
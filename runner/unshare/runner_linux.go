@@ -1,6 +1,8 @@
 package unshare
 
 import (
+	"github.com/tobiichi3227/go-sandbox/pkg/forkexec"
+	"github.com/tobiichi3227/go-sandbox/pkg/landlock"
 	"github.com/tobiichi3227/go-sandbox/pkg/mount"
 	"github.com/tobiichi3227/go-sandbox/pkg/rlimit"
 	"github.com/tobiichi3227/go-sandbox/pkg/seccomp"
@@ -25,18 +27,32 @@ type Runner struct {
 	// Resource limit set by set rlimit
 	RLimits []rlimit.RLimit
 
+	// CPU affinity, scheduling policy and I/O priority
+	Sched *forkexec.Sched
+
+	// DisableASLR disables address space randomization and TimeOffsets sets
+	// clock offsets in unshared time namespace for reproducible execution
+	DisableASLR bool
+	TimeOffsets *forkexec.TimeOffsets
+
 	// Resource limit enforced by tracer
 	Limit runner.Limit
 
 	// Seccomp defines the seccomp filter attach to the process (should be whitelist only)
 	Seccomp seccomp.Filter
 
+	// Landlock defines the landlock ruleset applied before seccomp, paths are
+	// resolved in the current mount namespace (before pivot_root)
+	Landlock *landlock.Ruleset
+
 	// New root
 	Root string
 
 	// Mount syscalls
 	Mounts []mount.SyscallParams
 
+	// MaskPaths defines paths to be masked after pivot_root
+	// (default: mount.DefaultMaskPaths if nil, non-nil empty slice masks none)
 	MaskPaths []string
 
 	// hostname & domainname
@@ -49,4 +65,8 @@ type Runner struct {
 	SyncFunc func(pid int) error
 
 	CgroupFD uintptr
+
+	// FreezeOnLimit freezes the program before kill when the context is
+	// canceled (e.g. time limit hit)
+	FreezeOnLimit *runner.FreezeOnLimit
 }